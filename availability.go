@@ -0,0 +1,210 @@
+package BrickMosaic
+
+import (
+	"encoding/csv"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Availability records which colors each part was ever produced in, so that a plan never calls for
+// a part that cannot be bought, such as a 1x10 plate in LightReddishViolet. Parts are looked up by
+// their Id. Parts that are not listed at all are assumed to exist in every color. A nil
+// *Availability is valid and lists no parts.
+//
+// DefaultAvailability covers the standard parts. Which parts were made in which colors changes as
+// sets are released, so a more complete dataset, e.g. exported from BrickLink's or Rebrickable's
+// part color lists, can be read with ParseAvailability.
+type Availability struct {
+	colors map[string]map[BrickColor]bool
+}
+
+// NewAvailability returns an empty availability dataset.
+func NewAvailability() *Availability {
+	return &Availability{make(map[string]map[BrickColor]bool)}
+}
+
+// Add records that the part with the given id exists in the given color.
+func (a *Availability) Add(id string, c BrickColor) {
+	if a.colors[id] == nil {
+		a.colors[id] = make(map[BrickColor]bool)
+	}
+	a.colors[id][c] = true
+}
+
+// Listed determines whether the dataset has anything to say about the part.
+func (a *Availability) Listed(b Brick) bool {
+	return a != nil && a.colors[BaseBrick(b).Id()] != nil
+}
+
+// Available determines whether the part exists in the given color.
+func (a *Availability) Available(b Brick, c BrickColor) bool {
+	if !a.Listed(b) {
+		return true
+	}
+	return a.colors[BaseBrick(b).Id()][c]
+}
+
+// Colors returns the colors the part exists in, ordered by id, or nil if the part is not listed.
+func (a *Availability) Colors(b Brick) []BrickColor {
+	if !a.Listed(b) {
+		return nil
+	}
+	var colors []BrickColor
+	for c := range a.colors[BaseBrick(b).Id()] {
+		colors = append(colors, c)
+	}
+	sort.Sort(byColorId(colors))
+	return colors
+}
+
+// Parts returns the bricks that exist in the given color, in the order they are given.
+func (a *Availability) Parts(c BrickColor, bricks []Brick) []Brick {
+	var result []Brick
+	for _, b := range bricks {
+		if a.Available(b, c) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// ParseAvailability reads an availability dataset from CSV records of a part id followed by the
+// names of every color it exists in, e.g.
+//
+//	3005,White,Black,BrightRed
+//
+// A part may be listed on more than one line. Lines starting with # are ignored.
+func ParseAvailability(r io.Reader) (*Availability, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	a := NewAvailability()
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: wanted a part id and at least one color", i+1)
+		}
+		id := strings.TrimSpace(record[0])
+		for _, name := range record[1:] {
+			c := ColorForName(strings.TrimSpace(name))
+			if c == nil {
+				return nil, fmt.Errorf("line %d: unknown color %q", i+1, name)
+			}
+			a.Add(id, *c)
+		}
+	}
+	return a, nil
+}
+
+// Palette returns the colors of the palette in which some piece made from the bricks covers a single
+// cell in the orientation, i.e. the colors a mosaic can be filled in. If no piece made from the bricks
+// ever covers a single cell, the palette is returned as it is.
+func (a *Availability) Palette(p color.Palette, bricks []Brick, o ViewOrientation) color.Palette {
+	if !hasFiller(PiecesForOrientation(o, bricks)) {
+		return p
+	}
+	var result color.Palette
+	for _, c := range p {
+		bc, ok := c.(BrickColor)
+		if !ok || hasFiller(PiecesForOrientation(o, a.Parts(bc, bricks))) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// hasFiller determines whether there is a piece among the pieces that covers a single cell, which
+// any gap in the mosaic can be filled with. Which parts do depends on the orientation: seen from the
+// side, a 1x1 brick covers three cells and only a 1x1 plate covers one.
+func hasFiller(pieces []MosaicPiece) bool {
+	for _, p := range pieces {
+		if len(p.Extent()) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	defaultAvailability     *Availability
+	defaultAvailabilityOnce sync.Once
+)
+
+// DefaultAvailability returns the colors the standard bricks and plates were made in. It lists the
+// solid colors each part was sold in, and the transparent colors of the small plates and 1x1
+// bricks; the rarer colors of the palette, e.g. the metallics, are left out of every part.
+func DefaultAvailability() *Availability {
+	defaultAvailabilityOnce.Do(func() {
+		a, err := ParseAvailability(strings.NewReader(availabilityData))
+		if err != nil {
+			panic(fmt.Sprintf("bad availability data: %v", err))
+		}
+		defaultAvailability = a
+	})
+	return defaultAvailability
+}
+
+// availabilityData is the dataset behind DefaultAvailability, in the form ParseAvailability reads.
+// Each part's colors are split over lines: the classic and core colors, the newer ones, and the
+// transparent ones.
+const availabilityData = `# Plates
+3024,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3024,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3024,LightReddishViolet
+3024,Transparent,TrRed,TrBlue,TrLgBlue,TrYellow,TrGreen,TrFluReddishOrange,TrFluGreen,TrBrown
+3023,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3023,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3023,LightReddishViolet
+3023,Transparent,TrRed,TrBlue,TrLgBlue,TrYellow,TrGreen,TrFluReddishOrange,TrFluGreen,TrBrown
+3623,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3623,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3623,Transparent
+3710,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3710,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3710,Transparent
+3666,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3666,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen
+3460,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3460,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,MediumLilac,DarkOrange,Nougat,BrightBluishGreen,LightRoyalBlue
+4477,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,DarkGreen,EarthBlue,EarthGreen,DarkRed
+4477,BrYellowishGreen,Sand,SandBlue
+2420,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+2420,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+# Bricks
+3005,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3005,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3005,LightReddishViolet
+3005,Transparent,TrRed,TrBlue,TrLgBlue,TrYellow,TrGreen,TrFluReddishOrange,TrFluGreen,TrBrown
+3004,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3004,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3004,LightReddishViolet
+3622,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3622,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3010,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3010,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3009,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3009,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen
+3008,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3008,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,MediumLilac,DarkOrange,BrightBluishGreen
+3003,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3003,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3003,LightReddishViolet
+3002,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3002,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3001,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+3001,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+3001,LightReddishViolet
+2456,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,DarkRed,BrightOrange
+2456,BrYellowishGreen,MediumBlue,Sand,SandBlue
+3007,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,DarkRed
+2357,White,Black,BrightRed,BrightBlue,BrightYellow,Grey,DarkGrey,MediumStoneGrey,DarkStoneGrey,ReddishBrown,BrickYellow,SandYellow,BrightGreen,DarkGreen,EarthBlue,EarthGreen,DarkRed,BrightOrange,EarthOrange
+2357,BrYellowishGreen,Sand,SandBlue,MediumBlue,BrightPurple,LightPurple,MediumLilac,BrightReddishViolet,FlameYellowishOrange,CoolYellow,LightRoyalBlue,DarkOrange,Nougat,BrightBluishGreen,BrightViolet
+`
@@ -0,0 +1,145 @@
+package BrickMosaic
+
+import (
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAvailability(t *testing.T) {
+	input := "# part,colors\n4477,White,Black\n3005,White\n3005,BrightRed\n"
+	a, err := ParseAvailability(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	for _, test := range []struct {
+		b    Brick
+		c    BrickColor
+		want bool
+	}{
+		{OneByTenPlate, Black, true},
+		{OneByTenPlate, LightReddishViolet, false},
+		{OneByOne, BrightRed, true},
+		// Not listed, so assumed to exist.
+		{TwoByFour, LightReddishViolet, true},
+		{StudsOutPiece(OneByOne), White, true},
+	} {
+		if got := a.Available(test.b, test.c); got != test.want {
+			t.Errorf("for %v in %v wanted %v got %v", test.b.Name(), test.c.name, test.want, got)
+		}
+	}
+	if got, want := a.Colors(OneByOne), []BrickColor{White, BrightRed}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v got %v", want, got)
+	}
+	if got, want := a.Parts(Black, []Brick{OneByOne, OneByTenPlate, TwoByFour}), []Brick{OneByTenPlate, TwoByFour}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v got %v", want, got)
+	}
+
+	for _, input := range []string{"3005", "3005,Plaid"} {
+		if _, err := ParseAvailability(strings.NewReader(input)); err == nil {
+			t.Errorf("for %q wanted an error", input)
+		}
+	}
+}
+
+func TestCreateGridMosaicWithAvailability(t *testing.T) {
+	a := NewAvailability()
+	a.Add(OneByFour.Id(), Black)
+	opts := MosaicOptions{
+		Bricks:       []Brick{OneByFour, OneByOne, OneByOnePlate},
+		Availability: a,
+	}
+	// There are no 1x4s in red, so it takes four 1x1s.
+	plan, err := CreateGridMosaic(uniformIdeal{StudsTop, 3, 4, BrightRed}, GreedySolve, opts)
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	if got := len(plan.Pieces()); got != 4 {
+		t.Errorf("wanted 4 pieces got %d", got)
+	}
+
+	// Once the 1x1 plates are listed without red, red cannot be filled at all.
+	a.Add(OneByOnePlate.Id(), Black)
+	if plan, err := CreateGridMosaic(uniformIdeal{StudsTop, 3, 4, BrightRed}, GreedySolve, opts); plan != nil || err == nil {
+		t.Errorf("wanted an error for a color without a 1x1, got %v", err)
+	}
+}
+
+func TestCreateGridMosaicNeedsSingleCellFiller(t *testing.T) {
+	// Seen from the side, a 1x1 brick covers three cells, so it cannot fill every gap; only the 1x1
+	// plate can, and it is only available in White.
+	a := NewAvailability()
+	a.Add(OneByOnePlate.Id(), White)
+	opts := MosaicOptions{
+		Bricks:       []Brick{OneByOne, OneByOnePlate, OneByTwoPlate},
+		Availability: a,
+	}
+	for _, o := range []ViewOrientation{StudsTop, StudsRight} {
+		_, err := CreateGridMosaic(uniformIdeal{o, 4, 3, BrightRed}, GreedySolve, opts)
+		if err == nil || !strings.Contains(err.Error(), "no 1x1 part is available in BrightRed") {
+			t.Errorf("for %v wanted an error for BrightRed without a 1x1 plate got %v", o, err)
+		}
+	}
+	// Facing the viewer, the 1x1 brick covers a single cell.
+	if _, err := CreateGridMosaic(uniformIdeal{StudsOut, 4, 3, BrightRed}, GreedySolve, opts); err != nil {
+		t.Errorf("for %v wanted no error got %v", StudsOut, err)
+	}
+	// Bricks alone never had a single cell filler on their side, so the availability is not to blame.
+	opts.Bricks = []Brick{OneByOne, OneByTwo}
+	if _, err := CreateGridMosaic(uniformIdeal{StudsTop, 3, 4, BrightRed}, GreedySolve, opts); err != nil {
+		t.Errorf("for bricks alone wanted no error got %v", err)
+	}
+}
+
+func TestDefaultAvailability(t *testing.T) {
+	a := DefaultAvailability()
+	for _, b := range Pieces {
+		if !a.Listed(b) {
+			t.Errorf("wanted %v to be listed", b.Name())
+		}
+		for _, c := range []BrickColor{White, Black, BrightRed, MediumStoneGrey} {
+			if !a.Available(b, c) {
+				t.Errorf("wanted %v in %v", b.Name(), c.name)
+			}
+		}
+	}
+	for _, test := range []struct {
+		b    Brick
+		c    BrickColor
+		want bool
+	}{
+		{OneByTenPlate, LightReddishViolet, false},
+		{OneByOnePlate, LightReddishViolet, true},
+		{OneByOnePlate, TrRed, true},
+		{TwoByFour, TrRed, false},
+		{OneByOnePlate, SilverFlipFlop, false},
+	} {
+		if got := a.Available(test.b, test.c); got != test.want {
+			t.Errorf("for %v in %v wanted %v got %v", test.b.Name(), test.c.name, test.want, got)
+		}
+	}
+}
+
+func TestAvailabilityPalette(t *testing.T) {
+	a := NewAvailability()
+	a.Add(OneByOnePlate.Id(), White)
+	a.Add(OneByOne.Id(), White)
+	a.Add(OneByOne.Id(), Black)
+	p := color.Palette{White, Black, BrightRed}
+	for _, test := range []struct {
+		o      ViewOrientation
+		bricks []Brick
+		want   color.Palette
+	}{
+		// Red is made in nothing, and on its side a 1x1 brick covers three cells.
+		{StudsOut, []Brick{OneByOne, OneByOnePlate}, color.Palette{White, Black}},
+		{StudsTop, []Brick{OneByOne, OneByOnePlate}, color.Palette{White}},
+		// Without a single cell filler to begin with, there is nothing to leave out.
+		{StudsTop, []Brick{OneByOne}, p},
+	} {
+		if got := a.Palette(p, test.bricks, test.o); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("for %v with %v wanted %v got %v", test.o, test.bricks, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// Each cell of the mosaic covers many pixels of the image, which have to be reduced to a single
+// color. Averaging the sRGB values, as AverageColor does, darkens cells with a lot of contrast:
+// sRGB is gamma encoded, so half way between black and white in sRGB is much darker than an even
+// mix of the two looks. Averaging in linear RGB mixes light the way the eye does, and averaging
+// in CIELAB mixes colors evenly by how they look.
+//
+// The rows and columns of the mosaic rarely line up with the pixels of the image, so a cell may
+// cover only part of the pixels along its edges. Each pixel counts in proportion to how much of it
+// the cell covers, and how opaque it is: a transparent pixel has no color to give the cell.
+// Whether a mostly transparent cell is left out of the mosaic is up to the Mask.
+
+// ColorSpace is the space colors are reduced in.
+type ColorSpace int
+
+const (
+	// SRGBSpace reduces the gamma encoded sRGB values, like AverageColor.
+	SRGBSpace ColorSpace = iota
+	// LinearSpace reduces linear RGB, the amount of light in each channel.
+	LinearSpace
+	// LabSpace reduces CIELAB values.
+	LabSpace
+)
+
+func (s ColorSpace) String() string {
+	switch s {
+	case SRGBSpace:
+		return "srgb"
+	case LinearSpace:
+		return "linear"
+	case LabSpace:
+		return "lab"
+	}
+	return fmt.Sprintf("ColorSpace(%d)", int(s))
+}
+
+// ColorSpaceForName returns the color space whose String matches name.
+func ColorSpaceForName(name string) (ColorSpace, error) {
+	for _, s := range []ColorSpace{SRGBSpace, LinearSpace, LabSpace} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown color space %q; wanted one of srgb, linear or lab", name)
+}
+
+// Reducer is how the colors of a cell are combined into one.
+type Reducer int
+
+const (
+	// MeanReducer takes the mean of each channel.
+	MeanReducer Reducer = iota
+	// MedianReducer takes the median of each channel, which ignores a few stray pixels.
+	MedianReducer
+	// ModeReducer takes the dominant color: the pixels are sorted into a histogram of similar
+	// colors, and the mean of the fullest bin is used. Unlike the mean and median it never makes
+	// up a color that is not in the image.
+	ModeReducer
+)
+
+func (r Reducer) String() string {
+	switch r {
+	case MeanReducer:
+		return "mean"
+	case MedianReducer:
+		return "median"
+	case ModeReducer:
+		return "mode"
+	}
+	return fmt.Sprintf("Reducer(%d)", int(r))
+}
+
+// ReducerForName returns the reducer whose String matches name.
+func ReducerForName(name string) (Reducer, error) {
+	for _, r := range []Reducer{MeanReducer, MedianReducer, ModeReducer} {
+		if r.String() == name {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown reducer %q; wanted one of mean, median or mode", name)
+}
+
+// sample is a pixel's color in the color space being reduced in, along with how much of the
+// pixel the cell covers, times its opacity.
+type sample struct {
+	v      [3]float64
+	weight float64
+	// bin is the histogram bin of the pixel's color, for ModeReducer.
+	bin uint32
+}
+
+// CellColor reduces the pixels of the image within the rectangle from (x0, y0) to (x1, y1) to a
+// single opaque color. The bounds need not fall on pixel boundaries; pixels that are partly inside
+// count in proportion to how much of them is inside, and translucent pixels in proportion to their
+// opacity. If every pixel is transparent, the result is too.
+func CellColor(img image.Image, x0, y0, x1, y1 float64, space ColorSpace, r Reducer) color.Color {
+	var samples []sample
+	total := 0.0
+	eachPixel(img, x0, y0, x1, y1, func(c color.Color, weight float64) {
+		s := newSample(c, space, weight)
+		samples = append(samples, s)
+		total += s.weight
+	})
+	if total == 0 {
+		return color.RGBA{}
+	}
+	switch r {
+	case MedianReducer:
+		return fromSpace(medianSample(samples), space)
+	case ModeReducer:
+		return fromSpace(meanSample(modeSamples(samples)), space)
+	}
+	return fromSpace(meanSample(samples), space)
+}
+
+// eachPixel calls f with each pixel of the image within the rectangle from (x0, y0) to (x1, y1),
+// and how much of the pixel is inside.
+func eachPixel(img image.Image, x0, y0, x1, y1 float64, f func(c color.Color, weight float64)) {
+	for y := int(math.Floor(y0)); float64(y) < y1; y++ {
+		dy := math.Min(float64(y+1), y1) - math.Max(float64(y), y0)
+		for x := int(math.Floor(x0)); float64(x) < x1; x++ {
+			dx := math.Min(float64(x+1), x1) - math.Max(float64(x), x0)
+			if dx <= 0 || dy <= 0 || !(image.Point{x, y}.In(img.Bounds())) {
+				continue
+			}
+			f(img.At(x, y), dx*dy)
+		}
+	}
+}
+
+func newSample(c color.Color, space ColorSpace, weight float64) sample {
+	r, g, b, a := c.RGBA()
+	// The channels come premultiplied by alpha, which would count a transparent pixel as black.
+	if a > 0 && a < 0xffff {
+		r, g, b = r*0xffff/a, g*0xffff/a, b*0xffff/a
+	}
+	s := sample{weight: weight * float64(a) / 0xffff, bin: (r>>11)<<10 | (g>>11)<<5 | b>>11}
+	switch space {
+	case LinearSpace:
+		s.v = [3]float64{linearize(r), linearize(g), linearize(b)}
+	case LabSpace:
+		l := toLab(color.RGBA64{uint16(r), uint16(g), uint16(b), 0xffff})
+		s.v = [3]float64{l.L, l.A, l.B}
+	default:
+		s.v = [3]float64{float64(r), float64(g), float64(b)}
+	}
+	return s
+}
+
+// fromSpace converts a reduced sample back to an opaque color.
+func fromSpace(s sample, space ColorSpace) color.Color {
+	switch space {
+	case LinearSpace:
+		return color.RGBA{R: delinearize(s.v[0]), G: delinearize(s.v[1]), B: delinearize(s.v[2]), A: 255}
+	case LabSpace:
+		c := lab{s.v[0], s.v[1], s.v[2]}.rgba()
+		c.A = 255
+		return c
+	}
+	to8 := func(v float64) uint8 {
+		return uint8(math.Max(0, math.Min(255, math.Floor(v/257+0.5))))
+	}
+	return color.RGBA{R: to8(s.v[0]), G: to8(s.v[1]), B: to8(s.v[2]), A: 255}
+}
+
+// meanSample returns the weighted mean of the samples.
+func meanSample(samples []sample) sample {
+	var result sample
+	for _, s := range samples {
+		for i := range s.v {
+			result.v[i] += s.v[i] * s.weight
+		}
+		result.weight += s.weight
+	}
+	if result.weight == 0 {
+		return result
+	}
+	for i := range result.v {
+		result.v[i] /= result.weight
+	}
+	return result
+}
+
+// medianSample returns the weighted median of each channel of the samples.
+func medianSample(samples []sample) sample {
+	var result sample
+	channel := make(weightedValues, len(samples))
+	for i := range result.v {
+		for j, s := range samples {
+			channel[j] = weightedValue{s.v[i], s.weight}
+		}
+		result.v[i] = channel.median()
+	}
+	return result
+}
+
+type weightedValue struct {
+	v, weight float64
+}
+
+type weightedValues []weightedValue
+
+func (w weightedValues) Len() int {
+	return len(w)
+}
+
+func (w weightedValues) Less(i, j int) bool {
+	return w[i].v < w[j].v
+}
+
+func (w weightedValues) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+}
+
+// median returns the value at which half of the weight is below, sorting the values as it goes.
+func (w weightedValues) median() float64 {
+	sort.Sort(w)
+	total := 0.0
+	for _, v := range w {
+		total += v.weight
+	}
+	seen := 0.0
+	for _, v := range w {
+		seen += v.weight
+		if seen >= total/2 {
+			return v.v
+		}
+	}
+	return w[len(w)-1].v
+}
+
+// modeSamples returns the samples in the histogram bin with the most weight. Ties go to the bin
+// seen first.
+func modeSamples(samples []sample) []sample {
+	weights := make(map[uint32]float64)
+	var best uint32
+	for _, s := range samples {
+		weights[s.bin] += s.weight
+		if weights[s.bin] > weights[best] {
+			best = s.bin
+		}
+	}
+	var result []sample
+	for _, s := range samples {
+		if s.bin == best {
+			result = append(result, s)
+		}
+	}
+	return result
+}
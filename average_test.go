@@ -0,0 +1,104 @@
+package BrickMosaic
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// checkerboard returns an image of alternating black and white pixels.
+func checkerboard(size int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for x := 0; x < size; x++ {
+		for y := 0; y < size; y++ {
+			if (x+y)%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
+}
+
+func TestCellColor(t *testing.T) {
+	// Three quarters red, with one stray white pixel in the red, and a quarter blue.
+	mostlyRed := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			mostlyRed.Set(x, y, color.RGBA{200, 0, 0, 255})
+			if y == 3 {
+				mostlyRed.Set(x, y, color.RGBA{0, 0, 200, 255})
+			}
+		}
+	}
+	mostlyRed.Set(0, 0, color.White)
+	// The edge of an alpha masked outline: opaque red fading out to the right.
+	edge := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	edge.Set(0, 0, color.NRGBA{200, 0, 0, 255})
+	edge.Set(1, 0, color.NRGBA{200, 0, 0, 128})
+	edge.Set(2, 0, color.NRGBA{0, 0, 0, 0})
+
+	for _, test := range []struct {
+		name           string
+		img            image.Image
+		x0, y0, x1, y1 float64
+		space          ColorSpace
+		r              Reducer
+		want           color.RGBA
+	}{
+		{"srgb mean of black and white", checkerboard(2), 0, 0, 2, 2, SRGBSpace, MeanReducer, color.RGBA{128, 128, 128, 255}},
+		{"linear mean of black and white", checkerboard(2), 0, 0, 2, 2, LinearSpace, MeanReducer, color.RGBA{188, 188, 188, 255}},
+		{"lab mean of black and white", checkerboard(2), 0, 0, 2, 2, LabSpace, MeanReducer, color.RGBA{119, 119, 119, 255}},
+		{"partial pixels count for less", checkerboard(2), 0, 0, 1.5, 1, SRGBSpace, MeanReducer, color.RGBA{170, 170, 170, 255}},
+		{"median ignores the stray pixel", mostlyRed, 0, 0, 4, 4, SRGBSpace, MedianReducer, color.RGBA{200, 0, 0, 255}},
+		{"mode picks the dominant color", mostlyRed, 0, 0, 4, 4, LinearSpace, ModeReducer, color.RGBA{200, 0, 0, 255}},
+		{"mode of a single pixel", mostlyRed, 0, 3, 1, 4, LabSpace, ModeReducer, color.RGBA{0, 0, 200, 255}},
+		{"half transparent edge keeps its color", edge, 0, 0, 3, 1, SRGBSpace, MeanReducer, color.RGBA{200, 0, 0, 255}},
+		{"half transparent edge in linear", edge, 0, 0, 3, 1, LinearSpace, MeanReducer, color.RGBA{200, 0, 0, 255}},
+		{"half transparent edge in lab", edge, 0, 0, 3, 1, LabSpace, MeanReducer, color.RGBA{200, 0, 0, 255}},
+		{"transparent pixels do not win the median", edge, 0, 0, 3, 1, SRGBSpace, MedianReducer, color.RGBA{200, 0, 0, 255}},
+		{"transparent pixels do not win the mode", edge, 0, 0, 3, 1, SRGBSpace, ModeReducer, color.RGBA{200, 0, 0, 255}},
+		{"fully transparent", edge, 2, 0, 3, 1, SRGBSpace, MeanReducer, color.RGBA{}},
+	} {
+		if got := CellColor(test.img, test.x0, test.y0, test.x1, test.y1, test.space, test.r); got != test.want {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestPosterizeSpaces(t *testing.T) {
+	palette := color.Palette{Black, DarkGrey, LightGrey, White}
+	for _, test := range []struct {
+		space ColorSpace
+		want  BrickColor
+	}{
+		{SRGBSpace, DarkGrey},
+		{LinearSpace, LightGrey},
+	} {
+		// Each stud covers one and a half pixels of the checkerboard in each direction.
+		ideal := PosterizeWith(checkerboard(6), palette, 4, 4, StudsOut, PosterizeOptions{Space: test.space})
+		if got := ideal.Color(1, 1); got != test.want {
+			t.Errorf("for %v wanted %v got %v", test.space, test.want, got)
+		}
+	}
+}
+
+func TestColorSpaceAndReducerForName(t *testing.T) {
+	for _, s := range []ColorSpace{SRGBSpace, LinearSpace, LabSpace} {
+		if got, err := ColorSpaceForName(s.String()); err != nil || got != s {
+			t.Errorf("for %v got %v, %v", s, got, err)
+		}
+	}
+	for _, r := range []Reducer{MeanReducer, MedianReducer, ModeReducer} {
+		if got, err := ReducerForName(r.String()); err != nil || got != r {
+			t.Errorf("for %v got %v, %v", r, got, err)
+		}
+	}
+	if _, err := ColorSpaceForName("hsv"); err == nil {
+		t.Errorf("wanted an error for an unknown color space")
+	}
+	if _, err := ReducerForName("max"); err == nil {
+		t.Errorf("wanted an error for an unknown reducer")
+	}
+}
@@ -0,0 +1,196 @@
+package BrickMosaic
+
+import (
+	"context"
+	"fmt"
+)
+
+// A mosaic one piece thick is only held together where the pieces overlap. A StudsTop or
+// StudsRight wall has nothing joining the pieces side by side within a course, and a StudsOut
+// panel without a plate underneath has nothing joining its pieces at all. The backing is a
+// hidden second layer that covers the same cells as the mosaic, behind the wall or under the
+// studs, with its seams placed away from the seams of the mosaic so that each backing piece
+// locks together the visible pieces it spans.
+//
+// Only the seams that hold a layer together count: in a StudsTop wall the seams between
+// columns, in a StudsRight wall the seams between rows, and in a StudsOut panel all of them.
+
+// Backing describes the layer to build behind the mosaic.
+type Backing struct {
+	// Color of the whole backing. As it cannot be seen, the cheapest color will do.
+	Color BrickColor
+}
+
+// backingParts returns the parts the backing may be built from: the plates, for a StudsOut
+// panel, or any of the parts for a wall.
+func backingParts(o ViewOrientation, parts []Brick) []Brick {
+	if o != StudsOut {
+		return parts
+	}
+	var plates []Brick
+	for _, b := range parts {
+		if isPlate(b) {
+			plates = append(plates, b)
+		}
+	}
+	return plates
+}
+
+// build solves the backing for the plan with the pieces. The pieces that were not in stock are
+// returned along with any error from the solver.
+func (b Backing) build(ctx context.Context, p Plan, pieces []MosaicPiece, stock *Stock) (Solution, []Brick, error) {
+	if stock == nil {
+		s, err := SolveBacking(p, pieces)
+		return s, nil, err
+	}
+	g := backingGrid(p)
+	return solveWithStock(ctx, &g, BackingSolver(p), pieces, b.Color, stock)
+}
+
+// backingGrid returns a grid with the cells covered by the plan's pieces to be filled.
+func backingGrid(p Plan) Grid {
+	g := NewGrid(p.Orig().NumRows(), p.Orig().NumCols())
+	for _, b := range p.Pieces() {
+		for _, loc := range b.Extent() {
+			abs := b.Origin.Add(loc)
+			g.Set(abs.Row, abs.Col, ToBeFilled)
+		}
+	}
+	return g
+}
+
+// SolveBacking solves the backing for the plan with the pieces. The backing covers the same
+// cells as the plan's pieces. Each cell, top to bottom and left to right, is covered with the
+// piece that lines up with the fewest seams of the mosaic, then spans the most of them, then is
+// the largest and cheapest; further ties go to the first of the pieces.
+func SolveBacking(p Plan, pieces []MosaicPiece) (Solution, error) {
+	g := backingGrid(p)
+	return BackingSolver(p)(&g, pieces)
+}
+
+// BackingSolver returns a GridSolver that lays a backing behind the plan's pieces as
+// SolveBacking describes. The grid must be no bigger than the plan.
+func BackingSolver(p Plan) GridSolver {
+	rows, cols := p.Orig().NumRows(), p.Orig().NumCols()
+	owner := newOwnerGrid(rows, cols)
+	for i, b := range p.Pieces() {
+		for _, loc := range b.Extent() {
+			abs := b.Origin.Add(loc)
+			if abs.Row >= 0 && abs.Row < rows && abs.Col >= 0 && abs.Col < cols {
+				owner[abs.Row][abs.Col] = i + 1
+			}
+		}
+	}
+	var directions []Location
+	switch p.Orig().Orientation() {
+	case StudsTop:
+		directions = []Location{{0, -1}, {0, 1}}
+	case StudsRight:
+		directions = []Location{{-1, 0}, {1, 0}}
+	default:
+		directions = []Location{{-1, 0}, {1, 0}, {0, -1}, {0, 1}}
+	}
+	return func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+		return backingSolve(g, pieces, owner, directions)
+	}
+}
+
+// backingScore rates placing a piece in the backing; lower is better.
+type backingScore struct {
+	aligned, spanned, cells, cost int
+}
+
+func (s backingScore) less(o backingScore) bool {
+	if s.aligned != o.aligned {
+		return s.aligned < o.aligned
+	}
+	if s.spanned != o.spanned {
+		return s.spanned > o.spanned
+	}
+	if s.cells != o.cells {
+		return s.cells > o.cells
+	}
+	return s.cost < o.cost
+}
+
+// score counts the seams of the mosaic that the piece at origin would line up with along its
+// edges, and those it would span.
+func score(g *Grid, owner [][]int, directions []Location, p MosaicPiece, origin Location) backingScore {
+	inPiece := make(map[Location]bool)
+	for _, loc := range p.Extent() {
+		inPiece[origin.Add(loc)] = true
+	}
+	s := backingScore{cells: len(p.Extent()), cost: p.ApproximateCost()}
+	for cell := range inPiece {
+		for _, d := range directions {
+			n := cell.Add(d)
+			if g.outOfBounds(n.Row, n.Col) || owner[n.Row][n.Col] == 0 || owner[n.Row][n.Col] == owner[cell.Row][cell.Col] {
+				continue
+			}
+			if inPiece[n] {
+				s.spanned++
+			} else if g.Get(n.Row, n.Col) != Empty {
+				s.aligned++
+			}
+		}
+	}
+	// Each seam within the piece was seen from both sides.
+	s.spanned /= 2
+	return s
+}
+
+func backingSolve(g *Grid, pieces []MosaicPiece, owner [][]int, directions []Location) (Solution, error) {
+	originalGrid := g.Clone()
+	locs := make(map[Location]MosaicPiece)
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			if g.Get(row, col) != ToBeFilled {
+				continue
+			}
+			var best MosaicPiece
+			var bestOrigin Location
+			var bestScore backingScore
+			for _, p := range pieces {
+				a := AnchorCell(p.Extent(), UpperLeft)
+				origin := Location{row - a.Row, col - a.Col}
+				if !g.PieceFits(p.Extent(), origin) {
+					continue
+				}
+				if s := score(g, owner, directions, p, origin); best == nil || s.less(bestScore) {
+					best, bestOrigin, bestScore = p, origin, s
+				}
+			}
+			if best == nil {
+				continue
+			}
+			locs[bestOrigin] = best
+			for _, pieceLoc := range best.Extent() {
+				absLoc := bestOrigin.Add(pieceLoc)
+				g.State[absLoc.Row][absLoc.Col] = Filled
+			}
+		}
+	}
+	if g.Any(ToBeFilled) {
+		return Solution{originalGrid, locs}, fmt.Errorf("Following locations must still be filled: %v", g.Find(ToBeFilled))
+	}
+	return Solution{originalGrid, locs}, nil
+}
+
+// placeBacking returns the pieces of the backing solution placed in the BackingLayer.
+func placeBacking(s Solution, c BrickColor, o ViewOrientation) []PlacedBrick {
+	var placed []PlacedBrick
+	for _, loc := range sortedLocations(s.Pieces) {
+		piece := s.Pieces[loc]
+		placed = append(placed, PlacedBrick{
+			Id:          len(placed),
+			Origin:      loc,
+			Locs:        piece.Extent(),
+			Color:       c,
+			Shape:       piece,
+			Orientation: o,
+			Rotated:     piece.Rotated(),
+			Layer:       BackingLayer,
+		})
+	}
+	return placed
+}
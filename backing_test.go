@@ -0,0 +1,121 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSolveBacking(t *testing.T) {
+	wallPieces := PiecesForOrientation(StudsTop, []Brick{OneByFour, OneByTwo, OneByOne})
+	platePieces := PiecesForOrientation(StudsOut, []Brick{OneByTwoPlate, OneByOnePlate})
+	for _, test := range []struct {
+		name   string
+		ideal  Ideal
+		bricks []PlacedBrick
+		pieces []MosaicPiece
+		want   map[Location]MosaicPiece
+	}{
+		{
+			name:  "wall of 1x4s",
+			ideal: uniformIdeal{StudsTop, 3, 8, BrightRed},
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsTop, OneByFour, Location{0, 0}),
+				placeBrick(2, StudsTop, OneByFour, Location{0, 4}),
+			},
+			pieces: wallPieces,
+			want: map[Location]MosaicPiece{
+				Location{0, 0}: wallPieces[1],
+				Location{0, 2}: wallPieces[0],
+				Location{0, 6}: wallPieces[1],
+			},
+		},
+		{
+			name:  "panel of 1x2s",
+			ideal: uniformIdeal{StudsOut, 1, 4, BrightRed},
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsOut, OneByTwoPlate, Location{0, 0}),
+				placeBrick(2, StudsOut, OneByTwoPlate, Location{0, 2}),
+			},
+			pieces: platePieces,
+			want: map[Location]MosaicPiece{
+				Location{0, 0}: StudsOutPiece(OneByOnePlate),
+				Location{0, 1}: StudsOutPiece(OneByTwoPlate),
+				Location{0, 3}: StudsOutPiece(OneByOnePlate),
+			},
+		},
+	} {
+		plan := brickPlan{test.ideal, test.bricks}
+		got, err := SolveBacking(plan, test.pieces)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got.Pieces, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got.Pieces)
+		}
+	}
+}
+
+func TestBacking(t *testing.T) {
+	for _, o := range []ViewOrientation{StudsOut, StudsTop, StudsRight} {
+		ideal := uniformIdeal{o, 9, 10, BrightRed}
+		plan, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{Backing: &Backing{Color: Black}})
+		if err != nil {
+			t.Fatalf("for %v wanted no error got %v", o, err)
+		}
+		optimized, err := OptimizePlan(plan, PiecesForOrientation(o, allBricks()), OptimizeOptions{Objective: PieceCountObjective})
+		if err != nil {
+			t.Fatalf("for %v wanted no error got %v", o, err)
+		}
+		for _, p := range []Plan{plan, optimized} {
+			backing := p.(LayeredPlan).Layer(BackingLayer)
+			covered := make(map[Location]int)
+			for _, b := range backing {
+				if b.Color != Black {
+					t.Errorf("for %v wanted a Black backing got %v", o, b.Color)
+				}
+				if o == StudsOut && !isPlate(BaseBrick(b.Shape)) {
+					t.Errorf("for %v wanted only plates got %v", o, b.Shape.Name())
+				}
+				for _, loc := range b.Extent() {
+					covered[b.Origin.Add(loc)]++
+				}
+			}
+			for row := 0; row < ideal.NumRows(); row++ {
+				for col := 0; col < ideal.NumCols(); col++ {
+					loc := Location{row, col}
+					if covered[loc] != 1 {
+						t.Errorf("for %v wanted %v backed once got %d", o, loc, covered[loc])
+					}
+				}
+			}
+			inventory := p.Inventory()
+			if got := len(inventory.PiecesForColor(Black)); got != len(backing) {
+				t.Errorf("for %v wanted %d backing pieces in the inventory got %d", o, len(backing), got)
+			}
+		}
+	}
+}
+
+func TestBackingNeedsFiller(t *testing.T) {
+	ideal := uniformIdeal{StudsOut, 4, 4, BrightRed}
+	opts := MosaicOptions{Bricks: Bricks, Backing: &Backing{Color: Black}}
+	if _, err := CreateGridMosaic(ideal, GreedySolve, opts); err == nil {
+		t.Errorf("wanted an error for a StudsOut backing without plates")
+	}
+}
+
+func TestSVGRenderBacking(t *testing.T) {
+	ideal := uniformIdeal{StudsTop, 6, 8, BrightRed}
+	plan, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{Backing: &Backing{Color: Black}})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	backing := LayerPlan(plan.(LayeredPlan), BackingLayer)
+	if got, want := len(backing.Pieces()), len(plan.(LayeredPlan).Layer(BackingLayer)); got != want {
+		t.Errorf("wanted %d pieces got %d", want, got)
+	}
+	if got := (SVGRenderer{}).Render(backing); !strings.Contains(got, "rgb(27,42,52)") {
+		t.Errorf("wanted the Black backing in the svg")
+	}
+}
@@ -0,0 +1,146 @@
+package BrickMosaic
+
+import (
+	"fmt"
+)
+
+// StudsOut mosaics are built on baseplates laid side by side. The mosaic is split into sections,
+// one per baseplate, numbered top to bottom, left to right starting from 1. Unless the baseplates
+// are bonded, no brick straddles two sections, so that each section can be built on its own
+// baseplate and the sections put together at the end.
+
+var (
+	// Baseplate16 represents a 16 x 16 baseplate. See http://brickowl.com/catalog/lego-baseplate-16-x-16-3867.
+	Baseplate16 = brick{
+		name:   "16x16 baseplate",
+		id:     "3867",
+		width:  16,
+		length: 16,
+		height: 1,
+		cost:   500,
+	}
+	// Baseplate32 represents a 32 x 32 baseplate. See http://brickowl.com/catalog/lego-baseplate-32-x-32-3811.
+	Baseplate32 = brick{
+		name:   "32x32 baseplate",
+		id:     "3811",
+		width:  32,
+		length: 32,
+		height: 1,
+		cost:   900,
+	}
+	// Baseplate48 represents a 48 x 48 baseplate. See http://brickowl.com/catalog/lego-baseplate-48-x-48-4186.
+	Baseplate48 = brick{
+		name:   "48x48 baseplate",
+		id:     "4186",
+		width:  48,
+		length: 48,
+		height: 1,
+		cost:   1800,
+	}
+
+	// Baseplates represents a slice of the square baseplates, smallest first.
+	Baseplates = []Brick{
+		Baseplate16,
+		Baseplate32,
+		Baseplate48,
+	}
+)
+
+// BaseplateForSize returns the square baseplate that is studs on a side.
+func BaseplateForSize(studs int) (Brick, error) {
+	for _, b := range Baseplates {
+		if b.Length() == studs {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("no %dx%d baseplate; wanted 16, 32 or 48", studs, studs)
+}
+
+// baseplateSections returns the baseplates needed under a rows x cols mosaic. Each covers the
+// cells of its section; those at the right and bottom edges may stick out past the mosaic.
+func baseplateSections(rows, cols int, plate Brick, c BrickColor) []PlacedBrick {
+	var result []PlacedBrick
+	size := plate.Length()
+	for top := 0; top < rows; top += size {
+		for left := 0; left < cols; left += size {
+			var locs []Location
+			for row := 0; row < size && top+row < rows; row++ {
+				for col := 0; col < size && left+col < cols; col++ {
+					locs = append(locs, Location{row, col})
+				}
+			}
+			result = append(result, PlacedBrick{
+				Id:          len(result) + 1,
+				Origin:      Location{top, left},
+				Locs:        locs,
+				Color:       c,
+				Shape:       plate,
+				Orientation: StudsOut,
+				Layer:       BaseplateLayer,
+			})
+		}
+	}
+	return result
+}
+
+// SectionSolver returns a GridSolver that splits the grid into size x size sections and solves
+// each of them on its own with solver, so that no piece straddles two sections.
+func SectionSolver(solver GridSolver, size int) GridSolver {
+	return func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+		originalGrid := g.Clone()
+		locs := make(map[Location]MosaicPiece)
+		for top := 0; top < g.Rows; top += size {
+			for left := 0; left < g.Cols; left += size {
+				section := NewGrid(minInt(size, g.Rows-top), minInt(size, g.Cols-left))
+				for row := 0; row < section.Rows; row++ {
+					for col := 0; col < section.Cols; col++ {
+						section.Set(row, col, g.Get(top+row, left+col))
+					}
+				}
+				if !section.Any(ToBeFilled) {
+					continue
+				}
+				solution, _ := solver(&section, pieces)
+				for origin, p := range solution.Pieces {
+					abs := Location{top + origin.Row, left + origin.Col}
+					locs[abs] = p
+					for _, rel := range p.Extent() {
+						cell := abs.Add(rel)
+						g.State[cell.Row][cell.Col] = Filled
+					}
+				}
+			}
+		}
+		if g.Any(ToBeFilled) {
+			return Solution{originalGrid, locs}, fmt.Errorf("Following locations must still be filled: %v", g.Find(ToBeFilled))
+		}
+		return Solution{originalGrid, locs}, nil
+	}
+}
+
+// splitSections divides the pieces of a solution that does not straddle sections into one
+// solution per size x size section.
+func splitSections(s Solution, size int) []Solution {
+	bySection := make(map[Location]map[Location]MosaicPiece)
+	var sections []Location
+	for _, origin := range sortedLocations(s.Pieces) {
+		section := Location{origin.Row / size, origin.Col / size}
+		if bySection[section] == nil {
+			bySection[section] = make(map[Location]MosaicPiece)
+			sections = append(sections, section)
+		}
+		bySection[section][origin] = s.Pieces[origin]
+	}
+	var result []Solution
+	for _, section := range sections {
+		result = append(result, Solution{s.Original, bySection[section]})
+	}
+	return result
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
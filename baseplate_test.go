@@ -0,0 +1,120 @@
+package BrickMosaic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBaseplateForSize(t *testing.T) {
+	for _, test := range []struct {
+		studs   int
+		want    Brick
+		wantErr bool
+	}{
+		{16, Baseplate16, false},
+		{32, Baseplate32, false},
+		{48, Baseplate48, false},
+		{24, nil, true},
+	} {
+		got, err := BaseplateForSize(test.studs)
+		if (err != nil) != test.wantErr {
+			t.Errorf("for %d wanted error %v got %v", test.studs, test.wantErr, err)
+		}
+		if got != test.want {
+			t.Errorf("for %d wanted %v got %v", test.studs, test.want, got)
+		}
+	}
+}
+
+func TestBaseplatesInInventory(t *testing.T) {
+	ideal := uniformIdeal{StudsOut, 20, 40, BrightRed}
+	plan, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{Baseplate: Baseplate16})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	plates := plan.(LayeredPlan).Layer(BaseplateLayer)
+	if len(plates) != 6 {
+		t.Fatalf("wanted 6 baseplates got %d", len(plates))
+	}
+	for i, p := range plates {
+		if p.Id != i+1 {
+			t.Errorf("for baseplate %d wanted id %d got %d", i, i+1, p.Id)
+		}
+	}
+	inventory := plan.Inventory()
+	if got := len(inventory.PiecesForColor(MediumStoneGrey)); got != 6 {
+		t.Errorf("wanted 6 baseplates in the inventory got %d", got)
+	}
+	if problems := ValidatePlan(plan); len(problems) != 0 {
+		t.Errorf("wanted no problems got %v", problems)
+	}
+}
+
+// straddles returns the pieces that cover cells of more than one section.
+func straddles(p Plan, size int) []PlacedBrick {
+	var result []PlacedBrick
+	for _, b := range p.Pieces() {
+		section := Location{b.Origin.Row / size, b.Origin.Col / size}
+		for _, loc := range b.Extent() {
+			cell := b.Origin.Add(loc)
+			if (Location{cell.Row / size, cell.Col / size}) != section {
+				result = append(result, b)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func TestBaseplateSections(t *testing.T) {
+	ideal := uniformIdeal{StudsOut, 4, 20, BrightRed}
+	for _, test := range []struct {
+		name string
+		bond bool
+		want bool
+	}{
+		{"separate", false, false},
+		{"bonded", true, true},
+	} {
+		opts := MosaicOptions{
+			Bricks:         []Brick{OneBySix, OneByOne},
+			Baseplate:      Baseplate16,
+			BondBaseplates: test.bond,
+		}
+		plan, err := CreateGridMosaic(ideal, GreedySolve, opts)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if got := len(straddles(plan, 16)) > 0; got != test.want {
+			t.Errorf("for %q wanted straddling pieces %v got %v", test.name, test.want, got)
+		}
+		optimized, err := OptimizePlan(plan, PiecesForOrientation(StudsOut, allBricks()), OptimizeOptions{Objective: PieceCountObjective})
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if !test.bond && len(straddles(optimized, 16)) > 0 {
+			t.Errorf("for %q optimizing made pieces straddle baseplates: %v", test.name, straddles(optimized, 16))
+		}
+	}
+}
+
+func TestBaseplatesNeedStudsOut(t *testing.T) {
+	ideal := uniformIdeal{StudsTop, 4, 4, BrightRed}
+	if _, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{Baseplate: Baseplate16}); err == nil {
+		t.Errorf("wanted an error for baseplates under a StudsTop mosaic")
+	}
+}
+
+func TestSVGRenderBaseplates(t *testing.T) {
+	ideal := uniformIdeal{StudsOut, 20, 20, BrightRed}
+	plan, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{Baseplate: Baseplate16})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	got := SVGRenderer{}.Render(plan)
+	for _, want := range []string{"baseplate-1", "baseplate-4"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("wanted %q in the svg", want)
+		}
+	}
+}
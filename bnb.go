@@ -0,0 +1,277 @@
+package BrickMosaic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// The branch and bound solver searches through every way of tiling the grid, depth first, visiting
+// cells in the same column major order as the exact solver. The best complete tiling found so far is
+// an upper bound on the cost; a partial tiling is abandoned as soon as its cost plus a lower bound on
+// the cost of covering the remaining cells reaches that upper bound. The search starts from the
+// GreedyMinCostSolve result, so it always has an answer to give back when it runs out of time.
+
+// checkInterval is how many search nodes are visited between checks for cancellation.
+const checkInterval = 1024
+
+// BoundReport describes the quality of a solution found by BranchAndBoundSolve.
+type BoundReport struct {
+	// Cost is the cost of the best solution found, in cents.
+	Cost int
+	// LowerBound is a cost, in cents, that no complete solution can beat.
+	LowerBound int
+	// Optimal indicates that the search finished, so Cost is the best possible.
+	Optimal bool
+	// Nodes is the number of partial solutions visited.
+	Nodes int
+}
+
+// Gap returns how far the cost is from the lower bound, as a fraction of the cost. A gap of 0
+// means that the solution is optimal; a large gap means a longer search might be worthwhile.
+func (b BoundReport) Gap() float64 {
+	if b.Cost <= 0 || b.Cost <= b.LowerBound {
+		return 0
+	}
+	return float64(b.Cost-b.LowerBound) / float64(b.Cost)
+}
+
+// Add combines two reports, e.g. for different grids of the same mosaic.
+func (b BoundReport) Add(o BoundReport) BoundReport {
+	return BoundReport{
+		Cost:       b.Cost + o.Cost,
+		LowerBound: b.LowerBound + o.LowerBound,
+		Optimal:    b.Optimal && o.Optimal,
+		Nodes:      b.Nodes + o.Nodes,
+	}
+}
+
+func (b BoundReport) String() string {
+	return fmt.Sprintf("cost %d, lower bound %d, gap %.1f%%, optimal %v, %d nodes", b.Cost, b.LowerBound, 100*b.Gap(), b.Optimal, b.Nodes)
+}
+
+// AnytimeSolver returns a GridSolver that runs BranchAndBoundSolve for at most timeout on each
+// grid. If report is non nil, it is called with the BoundReport of every grid solved; when colors
+// are solved in parallel it may be called from several goroutines at once.
+func AnytimeSolver(timeout time.Duration, report func(BoundReport)) GridSolver {
+	return AnytimeContextSolver(timeout, report).Bind(context.Background())
+}
+
+// AnytimeContextSolver is like AnytimeSolver, but also stops once the context it is given is done,
+// in which case it returns the best solution found along with the context's error.
+func AnytimeContextSolver(timeout time.Duration, report func(BoundReport)) ContextSolver {
+	return func(parent context.Context, g *Grid, pieces []MosaicPiece) (Solution, error) {
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		solution, r, err := BranchAndBoundSolve(ctx, g, pieces)
+		if report != nil {
+			report(r)
+		}
+		if parent.Err() != nil {
+			return solution, parent.Err()
+		}
+		return solution, err
+	}
+}
+
+// BranchAndBoundSolve searches for the cheapest way to fill the grid until the search is complete
+// or the context is done, whichever comes first. It returns the best solution found along with a
+// report of how close that solution is to optimal.
+func BranchAndBoundSolve(ctx context.Context, g *Grid, pieces []MosaicPiece) (Solution, BoundReport, error) {
+	originalGrid := g.Clone()
+	locs := make(map[Location]MosaicPiece)
+	report := BoundReport{Optimal: true}
+	sorted := anchorPieces(sortByCostPerCell(pieces))
+	for _, region := range regions(g) {
+		sub := region.grid(g)
+		s := newBnbSearch(ctx, &sub, region, sorted)
+		// If some cell cannot be covered by any piece, there is no point in searching.
+		if !math.IsInf(s.rootBound, 1) {
+			s.search(0)
+		}
+
+		regionReport := s.report()
+		report = report.Add(regionReport)
+		for _, p := range s.best {
+			locs[p.origin] = p.piece
+			for _, pieceLoc := range p.piece.Extent() {
+				absLoc := p.origin.Add(pieceLoc)
+				g.State[absLoc.Row][absLoc.Col] = Filled
+			}
+		}
+	}
+	if g.Any(ToBeFilled) {
+		return Solution{originalGrid, locs}, report, fmt.Errorf("Following locations must still be filled: %v", g.Find(ToBeFilled))
+	}
+	return Solution{originalGrid, locs}, report, nil
+}
+
+// bnbSearch holds the state of the search over a single region.
+type bnbSearch struct {
+	ctx    context.Context
+	g      *Grid
+	cells  []Location
+	pieces []anchoredPiece
+
+	// cellBound is the cheapest that each cell could possibly be covered for.
+	cellBound map[Location]float64
+	// rootBound is the lower bound for the whole region.
+	rootBound float64
+	// remaining is the lower bound for the cells that are not yet covered.
+	remaining float64
+
+	cost  int
+	trail []placement
+
+	best      []placement
+	bestCost  int
+	found     bool
+	nodes     int
+	cancelled bool
+}
+
+func newBnbSearch(ctx context.Context, g *Grid, r region, pieces []anchoredPiece) *bnbSearch {
+	s := &bnbSearch{
+		ctx:       ctx,
+		g:         g,
+		pieces:    pieces,
+		cellBound: make(map[Location]float64),
+	}
+	// Visit the cells in column major order, the same order the pieces are anchored in.
+	for col := r.minCol; col <= r.maxCol; col++ {
+		for row := r.minRow; row <= r.maxRow; row++ {
+			if g.Get(row, col) == ToBeFilled {
+				s.cells = append(s.cells, Location{row, col})
+				s.cellBound[Location{row, col}] = math.Inf(1)
+			}
+		}
+	}
+	for _, cell := range s.cells {
+		for _, ap := range pieces {
+			if !s.fits(ap, cell) {
+				continue
+			}
+			perCell := costPerCell(ap.piece)
+			for _, loc := range ap.cells(cell) {
+				if perCell < s.cellBound[loc] {
+					s.cellBound[loc] = perCell
+				}
+			}
+		}
+	}
+	for _, cell := range s.cells {
+		s.rootBound += s.cellBound[cell]
+	}
+	s.remaining = s.rootBound
+
+	// The greedy solution is the initial upper bound. Even if it leaves holes, it is better than
+	// nothing should the search run out of time before finding a complete tiling.
+	greedyGrid := g.Clone()
+	greedy, err := GreedyMinCostSolve(&greedyGrid, piecesOf(pieces))
+	s.found = err == nil
+	s.bestCost = greedy.Cost()
+	for origin, p := range greedy.Pieces {
+		s.best = append(s.best, placement{origin: origin, piece: p})
+	}
+	return s
+}
+
+func piecesOf(anchored []anchoredPiece) []MosaicPiece {
+	pieces := make([]MosaicPiece, len(anchored))
+	for i, ap := range anchored {
+		pieces[i] = ap.piece
+	}
+	return pieces
+}
+
+// cells returns the absolute locations covered by the piece when anchored at loc.
+func (ap anchoredPiece) cells(loc Location) []Location {
+	result := []Location{loc}
+	for _, off := range ap.offsets {
+		result = append(result, loc.Add(off))
+	}
+	return result
+}
+
+func (s *bnbSearch) fits(ap anchoredPiece, loc Location) bool {
+	for _, cell := range ap.cells(loc) {
+		if s.g.Get(cell.Row, cell.Col) != ToBeFilled {
+			return false
+		}
+	}
+	return true
+}
+
+// search covers the cells from index i onwards, recording any tiling cheaper than the best so far.
+func (s *bnbSearch) search(i int) {
+	if s.cancelled {
+		return
+	}
+	s.nodes++
+	// Check on the very first node too, in case the context was done before the search started.
+	if s.nodes%checkInterval == 1 && s.ctx.Err() != nil {
+		s.cancelled = true
+		return
+	}
+	for i < len(s.cells) && s.g.Get(s.cells[i].Row, s.cells[i].Col) != ToBeFilled {
+		i++
+	}
+	if i == len(s.cells) {
+		if !s.found || s.cost < s.bestCost {
+			s.found = true
+			s.bestCost = s.cost
+			s.best = append([]placement(nil), s.trail...)
+		}
+		return
+	}
+	// Costs are whole cents, so a partial solution is only worth pursuing if it could beat the
+	// best by at least one.
+	if s.found && s.cost+int(math.Ceil(s.remaining-1e-9)) >= s.bestCost {
+		return
+	}
+	cell := s.cells[i]
+	for _, ap := range s.pieces {
+		if !s.fits(ap, cell) {
+			continue
+		}
+		covered := ap.cells(cell)
+		for _, loc := range covered {
+			s.g.Set(loc.Row, loc.Col, Filled)
+			s.remaining -= s.cellBound[loc]
+		}
+		s.cost += ap.piece.ApproximateCost()
+		s.trail = append(s.trail, placement{
+			origin: Location{cell.Row - ap.anchor.Row, cell.Col - ap.anchor.Col},
+			piece:  ap.piece,
+		})
+
+		s.search(i + 1)
+
+		s.trail = s.trail[:len(s.trail)-1]
+		s.cost -= ap.piece.ApproximateCost()
+		for _, loc := range covered {
+			s.g.Set(loc.Row, loc.Col, ToBeFilled)
+			s.remaining += s.cellBound[loc]
+		}
+		if s.cancelled {
+			return
+		}
+	}
+}
+
+// report summarizes the search over the region.
+func (s *bnbSearch) report() BoundReport {
+	r := BoundReport{Cost: s.bestCost, Nodes: s.nodes}
+	switch {
+	case !s.cancelled && s.found:
+		r.Optimal = true
+		r.LowerBound = s.bestCost
+	case !s.found:
+		// There is no complete tiling to bound.
+		r.LowerBound = s.bestCost
+	default:
+		r.LowerBound = int(math.Ceil(s.rootBound - 1e-9))
+	}
+	return r
+}
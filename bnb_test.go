@@ -0,0 +1,91 @@
+package BrickMosaic
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBranchAndBoundSolveGreedyFixtures(t *testing.T) {
+	for _, test := range greedySolveTests {
+		greedyGrid := test.g.Clone()
+		greedy, greedyErr := GreedySolve(&greedyGrid, test.p)
+
+		g := test.g.Clone()
+		got, report, err := BranchAndBoundSolve(context.Background(), &g, test.p)
+		if err != nil && !test.hasErr {
+			t.Errorf("for %q wanted no error got %v", test.name, err)
+		} else if err == nil && test.hasErr {
+			t.Errorf("for %q should have had an error", test.name)
+		}
+		if greedyErr != nil || err != nil {
+			continue
+		}
+		checkCovered(t, test.name, got)
+		if got.Cost() > greedy.Cost() {
+			t.Errorf("for %q cost %d exceeds greedy cost %d", test.name, got.Cost(), greedy.Cost())
+		}
+		if !report.Optimal || report.Gap() != 0 {
+			t.Errorf("for %q wanted an optimal solution, got report %v", test.name, report)
+		}
+	}
+}
+
+func TestBranchAndBoundMatchesExact(t *testing.T) {
+	pieces := PiecesForOrientation(StudsOut, allBricks())
+	exactGrid := WithState(4, 6, ToBeFilled)
+	exact, err := ExactMinCostSolve(&exactGrid, pieces)
+	if err != nil {
+		t.Fatalf("exact: wanted no error got %v", err)
+	}
+
+	g := WithState(4, 6, ToBeFilled)
+	got, report, err := BranchAndBoundSolve(context.Background(), &g, pieces)
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	checkCovered(t, "4x6", got)
+	if got.Cost() != exact.Cost() {
+		t.Errorf("wanted cost %d got %d", exact.Cost(), got.Cost())
+	}
+	if report.Cost != got.Cost() || report.LowerBound != got.Cost() || !report.Optimal {
+		t.Errorf("wanted optimal report with cost %d, got %v", got.Cost(), report)
+	}
+}
+
+func TestBranchAndBoundSolveCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	g := WithState(10, 10, ToBeFilled)
+	got, report, err := BranchAndBoundSolve(ctx, &g, PiecesForOrientation(StudsOut, allBricks()))
+	if err != nil {
+		t.Fatalf("the greedy solution should have been returned; got %v", err)
+	}
+	checkCovered(t, "cancelled", got)
+	if report.Optimal {
+		t.Errorf("cancelled search cannot be optimal: %v", report)
+	}
+	if report.LowerBound > report.Cost {
+		t.Errorf("lower bound exceeds cost: %v", report)
+	}
+}
+
+func TestAnytimeSolver(t *testing.T) {
+	var reports []BoundReport
+	solver := AnytimeSolver(10*time.Millisecond, func(r BoundReport) {
+		reports = append(reports, r)
+	})
+	g := WithState(30, 30, ToBeFilled)
+	got, err := solver(&g, PiecesForOrientation(StudsTop, allBricks()))
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	checkCovered(t, "anytime", got)
+	if len(reports) != 1 {
+		t.Fatalf("wanted 1 report got %d", len(reports))
+	}
+	if reports[0].Cost != got.Cost() {
+		t.Errorf("report cost %d does not match solution cost %d", reports[0].Cost, got.Cost())
+	}
+}
@@ -7,8 +7,7 @@ type Brick interface {
 	Width() int
 	Length() int
 	Height() int
-	// TODO(ndunn): This somehow has to take into account color
-	// Cost in cents.
+	// Cost in cents, whatever the color. A PriceTable gives the price in a particular color.
 	ApproximateCost() int
 }
 
@@ -24,6 +23,9 @@ type brick struct {
 	length int
 	// height is measured in terms of plates - a standard brick is 3 plates high.
 	height int
+	// footprint marks which studs of the width x length rectangle the brick covers when viewed
+	// from above, one bit per stud in row major order. 0 means the whole rectangle.
+	footprint uint64
 
 	// Cost in cents
 	cost int
@@ -53,6 +55,18 @@ func (b brick) ApproximateCost() int {
 	return b.cost
 }
 
+func (b brick) Footprint() []Location {
+	return RectPiece{b.width, b.length}.mask(b.footprint).Extent()
+}
+
+// Footprinted is implemented by bricks that do not cover their whole Width x Length rectangle
+// when viewed from above, such as corner plates.
+type Footprinted interface {
+	// Footprint returns the studs the brick covers when viewed from above, as locations within
+	// its Width rows and Length columns.
+	Footprint() []Location
+}
+
 var (
 	// OneByEight represents a 1 x 8 brick. See http://lego.wikia.com/wiki/Part_3008.
 	OneByEight = brick{
@@ -244,6 +258,33 @@ var (
 		cost: 10,
 	}
 
+	// CornerBrick represents a 2 x 2 corner brick, which covers three of the four studs of a 2 x 2.
+	// See http://brickowl.com/catalog/lego-brick-2-x-2-corner-2357.
+	CornerBrick = brick{
+		name:   "2x2 corner brick",
+		id:     "2357",
+		width:  2,
+		length: 2,
+		height: 3,
+		// ##
+		// #.
+		footprint: 0x7,
+		cost:      6,
+	}
+	// CornerPlate represents a 2 x 2 corner plate, which covers three of the four studs of a 2 x 2.
+	// See http://brickowl.com/catalog/lego-plate-2-x-2-corner-2420.
+	CornerPlate = brick{
+		name:   "2x2 corner plate",
+		id:     "2420",
+		width:  2,
+		length: 2,
+		height: 1,
+		// ##
+		// #.
+		footprint: 0x7,
+		cost:      3,
+	}
+
 	// Bricks represents a slice of all of the bricks (full height, not plates). They are listed in descending
 	// order of area.
 	Bricks = []Brick{
@@ -273,6 +314,13 @@ var (
 		OneByOnePlate,
 	}
 
+	// Corners represents the corner pieces. They are not among the standard Pieces, since they
+	// only make sense where the mosaic is viewed studs out; add them with a catalog.
+	Corners = []Brick{
+		CornerBrick,
+		CornerPlate,
+	}
+
 	// Pieces represents a slice of all of the standard Bricks; the concatenation of Bricks and Plates.
 	Pieces = allBricks()
 )
@@ -291,6 +339,11 @@ type MosaicPiece interface {
 	Piece
 	Rows() int
 	Cols() int
+	// Rotated reports whether the brick is turned from the way it normally faces in the
+	// orientation, e.g. a 2x4 brick laid as 4x2 when viewed from above.
+	Rotated() bool
+	// Turns is the number of quarter turns clockwise the brick is turned by.
+	Turns() int
 }
 
 type mosaicPiece struct {
@@ -298,11 +351,14 @@ type mosaicPiece struct {
 	// In whatever orientation the mosaic is facing. e.g. a 2x4 brick when viewed above has size 2x4.
 	// When viewed from the side, it has size 3x4 (3 plates high, 4 bricks wide)
 	Rect RectPiece
+	// shape marks which cells of Rect the piece covers, as for brick.footprint. 0 means all of them.
+	shape uint64
+	turns int
 }
 
 // Extent() fulfills Extent interface
 func (r mosaicPiece) Extent() []Location {
-	return r.Rect.Extent()
+	return r.Rect.mask(r.shape).Extent()
 }
 
 func (r mosaicPiece) Name() string {
@@ -337,23 +393,72 @@ func (r mosaicPiece) ApproximateCost() int {
 	return r.Brick.ApproximateCost()
 }
 
-// TODO(ndunn): This could either be facing horizontally or vertically. This is not taking
-// that into consideration.
+func (r mosaicPiece) Rotated() bool {
+	return r.turns != 0
+}
+
+func (r mosaicPiece) Turns() int {
+	return r.turns
+}
+
+// BaseBrick returns the prototypical brick behind b, stripped of any orientation in the mosaic.
+// Two pieces made from the same physical part have the same BaseBrick.
+func BaseBrick(b Brick) Brick {
+	switch p := b.(type) {
+	case mosaicPiece:
+		return BaseBrick(p.Brick)
+	case pricedPiece:
+		return BaseBrick(p.MosaicPiece)
+	}
+	return b
+}
+
+// StudsOutPiece is the brick viewed from above, lying horizontally.
 func StudsOutPiece(piece Brick) MosaicPiece {
+	return StudsOutTurnedPiece(piece, 0)
+}
+
+// StudsOutRotatedPiece is the brick viewed from above, turned to lie vertically.
+func StudsOutRotatedPiece(piece Brick) MosaicPiece {
+	return StudsOutTurnedPiece(piece, 1)
+}
+
+// StudsOutTurnedPiece is the brick viewed from above, turned clockwise by the given number of
+// quarter turns. Only bricks whose footprint is not a rectangle, such as corner plates, look any
+// different after more than one turn.
+func StudsOutTurnedPiece(piece Brick, turns int) MosaicPiece {
 	// Studs up, so rows = width, cols = length
 	r := RectPiece{piece.Width(), piece.Length()}
+	cells := r.Extent()
+	if f, ok := BaseBrick(piece).(Footprinted); ok {
+		cells = f.Footprint()
+	}
+	turns = ((turns % 4) + 4) % 4
+	for i := 0; i < turns; i++ {
+		// A clockwise quarter turn takes (row, col) to (col, rows - 1 - row).
+		turned := make([]Location, len(cells))
+		for j, loc := range cells {
+			turned[j] = Location{loc.Col, r.NumRows - 1 - loc.Row}
+		}
+		cells = turned
+		r = RectPiece{r.NumCols, r.NumRows}
+	}
 	return mosaicPiece{
 		Brick: piece,
 		Rect:  r,
+		shape: r.shapeOf(cells),
+		turns: turns,
 	}
 }
 
+// StudsTopPiece is the brick viewed from the side, studs up. Only the side facing the viewer is
+// part of the mosaic, so even a corner brick covers a rectangle of the grid.
 func StudsTopPiece(piece Brick) MosaicPiece {
 	// Studs to the top on side, so rows = height, cols = length
 	r := RectPiece{piece.Height(), piece.Length()}
 	return mosaicPiece{
-		piece,
-		r,
+		Brick: piece,
+		Rect:  r,
 	}
 }
 
@@ -361,18 +466,34 @@ func StudsRightPiece(piece Brick) MosaicPiece {
 	// Studs to the right on its side, so rows = length, cols = height
 	r := RectPiece{piece.Length(), piece.Height()}
 	return mosaicPiece{
-		piece,
-		r,
+		Brick: piece,
+		Rect:  r,
 	}
 }
 
+// PiecesForOrientation returns the pieces that the bricks make in the given orientation. When
+// viewed studs out, bricks can be turned, so every turn that gives a different shape is returned,
+// one after the other: both rotations of a 2x4, and all four of a corner plate.
 func PiecesForOrientation(o ViewOrientation, pieces []Brick) []MosaicPiece {
+	if o == StudsOut {
+		var result []MosaicPiece
+		for _, p := range pieces {
+			seen := make(map[mosaicPiece]bool)
+			for turns := 0; turns < 4; turns++ {
+				piece := StudsOutTurnedPiece(p, turns).(mosaicPiece)
+				// Pieces are the same shape if they only differ in how far they were turned.
+				shape := piece
+				shape.turns = 0
+				if !seen[shape] {
+					seen[shape] = true
+					result = append(result, piece)
+				}
+			}
+		}
+		return result
+	}
 	result := make([]MosaicPiece, len(pieces))
 	switch o {
-	case StudsOut:
-		for i, p := range pieces {
-			result[i] = StudsOutPiece(p)
-		}
 	case StudsTop:
 		for i, p := range pieces {
 			result[i] = StudsTopPiece(p)
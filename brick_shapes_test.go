@@ -133,3 +133,107 @@ func TestRectPieceExtent(t *testing.T) {
 		}
 	}
 }
+
+func TestPiecesForOrientationRotations(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		o      ViewOrientation
+		bricks []Brick
+		want   []RectPiece
+	}{
+		{
+			"studs out 2x4 can be turned",
+			StudsOut,
+			[]Brick{TwoByFour},
+			[]RectPiece{{2, 4}, {4, 2}},
+		},
+		{
+			"studs out 2x2 is the same either way",
+			StudsOut,
+			[]Brick{TwoByTwo},
+			[]RectPiece{{2, 2}},
+		},
+		{
+			"studs top is never turned",
+			StudsTop,
+			[]Brick{TwoByFour},
+			[]RectPiece{{3, 4}},
+		},
+	} {
+		var got []RectPiece
+		for _, p := range PiecesForOrientation(test.o, test.bricks) {
+			got = append(got, RectPiece{p.Rows(), p.Cols()})
+			if p.Rotated() != (p.Rows() != test.want[0].NumRows) {
+				t.Errorf("%q got rotated %v for %v", test.name, p.Rotated(), p)
+			}
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q want %v got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestRotatedPiecesAreTheSamePart(t *testing.T) {
+	ideal := uniformIdeal{StudsOut, 4, 2, BrightRed}
+	plan, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	pieces := plan.Pieces()
+	if len(pieces) != 1 || !pieces[0].Rotated {
+		t.Fatalf("wanted a single rotated 2x4 got %v", pieces)
+	}
+
+	inventory := MakeInventory()
+	inventory.Add(BrightRed, StudsOutPiece(TwoByFour))
+	inventory.Add(BrightRed, StudsOutRotatedPiece(TwoByFour))
+	if got, want := inventory.PiecesForColor(BrightRed), []Brick{TwoByFour, TwoByFour}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+}
+
+func TestCornerPieces(t *testing.T) {
+	var got [][]Location
+	for _, p := range PiecesForOrientation(StudsOut, []Brick{CornerPlate}) {
+		got = append(got, p.Extent())
+	}
+	want := [][]Location{
+		{{0, 0}, {0, 1}, {1, 0}},
+		{{0, 0}, {0, 1}, {1, 1}},
+		{{0, 1}, {1, 0}, {1, 1}},
+		{{0, 0}, {1, 0}, {1, 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v got %v", want, got)
+	}
+
+	// From the side, only the arm along the wall can be seen.
+	if got := StudsTopPiece(CornerBrick).Extent(); !reflect.DeepEqual(got, RectPiece{3, 2}.Extent()) {
+		t.Errorf("want %v got %v", RectPiece{3, 2}.Extent(), got)
+	}
+}
+
+func TestSolversPlaceCorners(t *testing.T) {
+	pieces := PiecesForOrientation(StudsOut, []Brick{CornerPlate, OneByOnePlate})
+	for _, test := range []struct {
+		name   string
+		solver GridSolver
+	}{
+		{"greedy", GreedySolve},
+		{"symmetrical", SymmetricalGreedySolve},
+		{"exact", ExactMinCostSolve},
+	} {
+		// An L that only a corner turned upside down fits.
+		g := WithState(2, 2, ToBeFilled)
+		g.Set(0, 0, Empty)
+		got, err := test.solver(&g, pieces)
+		if err != nil {
+			t.Errorf("for %q wanted no error got %v", test.name, err)
+			continue
+		}
+		want := map[Location]MosaicPiece{Location{0, 0}: StudsOutTurnedPiece(CornerPlate, 2)}
+		if !reflect.DeepEqual(got.Pieces, want) {
+			t.Errorf("for %q wanted %v got %v", test.name, want, got.Pieces)
+		}
+	}
+}
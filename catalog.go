@@ -0,0 +1,200 @@
+package BrickMosaic
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// A catalog is a list of the parts that may be used in a mosaic, read from a file instead of the
+// standard Pieces. Every part needs a name, LDraw id, width and length in studs, height in plates
+// and cost in cents. In JSON, the catalog is an array of parts:
+//
+//	[
+//	  {"name": "1x12 plate", "id": "60479", "width": 1, "length": 12, "height": 1, "cost": 20}
+//	]
+//
+// In CSV, the first line names the columns, which may come in any order:
+//
+//	name,id,width,length,height,cost
+//	1x12 plate,60479,1,12,1,20
+//
+// Parts that do not cover their whole width x length rectangle when viewed from above, such as
+// corner plates, also need a footprint: one string per row of studs, with # for each stud the
+// part covers and . for the rest. In JSON the footprint is an array of rows; in CSV it is an
+// optional column, with the rows separated by /:
+//
+//	{"name": "2x2 corner plate", "id": "2420", "width": 2, "length": 2, "height": 1, "cost": 3,
+//	 "footprint": ["##", "#."]}
+//
+//	name,id,width,length,height,cost,footprint
+//	2x2 corner plate,2420,2,2,1,3,##/#.
+//
+// Since # marks studs in a footprint, a CSV catalog cannot have comments.
+
+// catalogEntry is a single part in a JSON catalog.
+type catalogEntry struct {
+	Name   string `json:"name"`
+	Id     string `json:"id"`
+	Width  int    `json:"width"`
+	Length int    `json:"length"`
+	Height int    `json:"height"`
+	Cost   int    `json:"cost"`
+	// Footprint is empty for parts that cover their whole rectangle.
+	Footprint []string `json:"footprint"`
+}
+
+func (e catalogEntry) brick() (Brick, error) {
+	if e.Name == "" || e.Id == "" {
+		return nil, fmt.Errorf("part %+v needs a name and an id", e)
+	}
+	if e.Width <= 0 || e.Length <= 0 || e.Height <= 0 {
+		return nil, fmt.Errorf("part %v must have positive width, length and height", e.Id)
+	}
+	if e.Cost < 0 {
+		return nil, fmt.Errorf("part %v has negative cost %d", e.Id, e.Cost)
+	}
+	footprint, err := e.footprint()
+	if err != nil {
+		return nil, fmt.Errorf("part %v: %v", e.Id, err)
+	}
+	return brick{
+		name:      e.Name,
+		id:        e.Id,
+		width:     e.Width,
+		length:    e.Length,
+		height:    e.Height,
+		footprint: footprint,
+		cost:      e.Cost,
+	}, nil
+}
+
+// footprint converts the rows of the footprint to the mask used by brick.
+func (e catalogEntry) footprint() (uint64, error) {
+	if len(e.Footprint) == 0 {
+		return 0, nil
+	}
+	if e.Width*e.Length > 64 {
+		return 0, fmt.Errorf("footprint can cover at most 64 studs")
+	}
+	if len(e.Footprint) != e.Width {
+		return 0, fmt.Errorf("footprint has %d rows, wanted %d", len(e.Footprint), e.Width)
+	}
+	r := RectPiece{e.Width, e.Length}
+	var cells []Location
+	for row, studs := range e.Footprint {
+		if len(studs) != e.Length {
+			return 0, fmt.Errorf("footprint row %q should have %d studs", studs, e.Length)
+		}
+		for col, stud := range studs {
+			switch stud {
+			case '#':
+				cells = append(cells, Location{row, col})
+			case '.':
+			default:
+				return 0, fmt.Errorf("footprint row %q may only contain # and .", studs)
+			}
+		}
+	}
+	if len(cells) == 0 {
+		return 0, fmt.Errorf("footprint covers no studs")
+	}
+	return r.shapeOf(cells), nil
+}
+
+// LoadCatalog reads the parts in the catalog file at path. Files ending in .json are read as JSON;
+// anything else is read as CSV.
+func LoadCatalog(path string) ([]Brick, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return ParseCatalogJSON(f)
+	}
+	return ParseCatalogCSV(f)
+}
+
+// ParseCatalogJSON reads a catalog from a JSON array of parts.
+func ParseCatalogJSON(r io.Reader) ([]Brick, error) {
+	var entries []catalogEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return catalogBricks(entries)
+}
+
+// ParseCatalogCSV reads a catalog from CSV, whose first line names the columns.
+func ParseCatalogCSV(r io.Reader) ([]Brick, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("catalog is empty")
+	}
+	column := make(map[string]int)
+	for i, name := range records[0] {
+		column[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "id", "width", "length", "height", "cost"} {
+		if _, ok := column[name]; !ok {
+			return nil, fmt.Errorf("catalog is missing the %q column", name)
+		}
+	}
+
+	var entries []catalogEntry
+	for i, record := range records[1:] {
+		var dims [4]int
+		for j, name := range []string{"width", "length", "height", "cost"} {
+			n, err := strconv.Atoi(strings.TrimSpace(record[column[name]]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid %v %q", i+2, name, record[column[name]])
+			}
+			dims[j] = n
+		}
+		entry := catalogEntry{
+			Name:   strings.TrimSpace(record[column["name"]]),
+			Id:     strings.TrimSpace(record[column["id"]]),
+			Width:  dims[0],
+			Length: dims[1],
+			Height: dims[2],
+			Cost:   dims[3],
+		}
+		if i, ok := column["footprint"]; ok {
+			if footprint := strings.TrimSpace(record[i]); footprint != "" {
+				entry.Footprint = strings.Split(footprint, "/")
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return catalogBricks(entries)
+}
+
+func catalogBricks(entries []catalogEntry) ([]Brick, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("catalog has no parts")
+	}
+	seen := make(map[string]bool)
+	var bricks []Brick
+	for _, e := range entries {
+		b, err := e.brick()
+		if err != nil {
+			return nil, err
+		}
+		if seen[b.Id()] {
+			return nil, fmt.Errorf("part %v is listed more than once", b.Id())
+		}
+		seen[b.Id()] = true
+		bricks = append(bricks, b)
+	}
+	return bricks, nil
+}
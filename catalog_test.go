@@ -0,0 +1,87 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var oneByTwelvePlate = brick{
+	name:   "1x12 plate",
+	id:     "60479",
+	width:  1,
+	length: 12,
+	height: 1,
+	cost:   20,
+}
+
+func TestParseCatalog(t *testing.T) {
+	want := []Brick{oneByTwelvePlate, OneByOne}
+	for _, test := range []struct {
+		name  string
+		parse func(string) ([]Brick, error)
+		input string
+	}{
+		{
+			"json",
+			func(s string) ([]Brick, error) { return ParseCatalogJSON(strings.NewReader(s)) },
+			`[{"name": "1x12 plate", "id": "60479", "width": 1, "length": 12, "height": 1, "cost": 20},
+			  {"name": "1x1 brick", "id": "3005", "width": 1, "length": 1, "height": 3, "cost": 4}]`,
+		},
+		{
+			"csv",
+			func(s string) ([]Brick, error) { return ParseCatalogCSV(strings.NewReader(s)) },
+			"name,id,width,length,height,cost\n1x12 plate,60479,1,12,1,20\n1x1 brick,3005,1,1,3,4\n",
+		},
+		{
+			"csv columns in another order",
+			func(s string) ([]Brick, error) { return ParseCatalogCSV(strings.NewReader(s)) },
+			"id,name,cost,width,length,height\n60479,1x12 plate,20,1,12,1\n3005,1x1 brick,4,1,1,3\n",
+		},
+	} {
+		got, err := test.parse(test.input)
+		if err != nil {
+			t.Errorf("for %q wanted no error got %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("for %q wanted %v got %v", test.name, want, got)
+		}
+	}
+}
+
+func TestParseCatalogErrors(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"name,id,width,length,height\n1x1 brick,3005,1,1,3\n",
+		"name,id,width,length,height,cost\n1x1 brick,3005,1,one,3,6\n",
+		"name,id,width,length,height,cost\n1x1 brick,3005,0,1,3,6\n",
+		"name,id,width,length,height,cost\n,3005,1,1,3,6\n",
+		"name,id,width,length,height,cost\n1x1 brick,3005,1,1,3,6\n1x1 brick,3005,1,1,3,4\n",
+		"name,id,width,length,height,cost,footprint\n2x2 corner plate,2420,2,2,1,3,\n##/#.\n",
+	} {
+		if _, err := ParseCatalogCSV(strings.NewReader(input)); err == nil {
+			t.Errorf("for %q wanted an error", input)
+		}
+	}
+}
+
+func TestParseCatalogFootprint(t *testing.T) {
+	input := "name,id,width,length,height,cost,footprint\n" +
+		"2x2 corner plate,2420,2,2,1,3,##/#.\n" +
+		"1x1 plate,3024,1,1,1,5,\n"
+	got, err := ParseCatalogCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	if want := []Brick{CornerPlate, OneByOnePlate}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v got %v", want, got)
+	}
+
+	for _, footprint := range []string{"##", "##/#", "##/#x", "../.."} {
+		input := "name,id,width,length,height,cost,footprint\n2x2 corner plate,2420,2,2,1,3," + footprint + "\n"
+		if _, err := ParseCatalogCSV(strings.NewReader(input)); err == nil {
+			t.Errorf("for %q wanted an error", footprint)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+)
+
+// Picking a palette by hand is trial and error. ChoosePalette instead picks the brick colors that
+// best represent an image. The image is reduced to the cells of the mosaic, the same way it is
+// when posterized, and each cell is matched to its nearest color in the palette. The error of a
+// palette is the total distance between the cells and the colors they are matched to.
+//
+// The search is a greedy start followed by swaps, the way k-medoids clustering works, except
+// that the medoids may only be brick colors: colors are added one at a time, each time the one
+// that lowers the error the most, and then each chosen color is swapped for any other candidate
+// that lowers the error, until no swap helps. It is not guaranteed to find the best palette, but
+// every color it keeps pulls its weight.
+
+// PaletteOptions configures ChoosePalette.
+type PaletteOptions struct {
+	// Candidates are the colors to choose from. If nil, the FullPalette.
+	Candidates color.Palette
+	// Include are colors that must be in the palette, whether or not they are candidates.
+	Include []BrickColor
+	// Posterize is how the image is reduced to cells and how colors are compared; its Dither is
+	// not used.
+	Posterize PosterizeOptions
+}
+
+// ChoosePalette returns the k brick colors that match the image, reduced to rows x cols cells,
+// with the least error. The colors that had to be included come first, followed by the chosen
+// colors in the order they are in the candidates.
+func ChoosePalette(img image.Image, rows, cols, k int, opts PaletteOptions) ([]BrickColor, error) {
+	candidates := opts.Candidates
+	if candidates == nil {
+		candidates = FullPalette
+	}
+	var include []BrickColor
+	for _, c := range opts.Include {
+		if !included(include, c) {
+			include = append(include, c)
+		}
+	}
+	var colors []BrickColor
+	index := make(map[BrickColor]int)
+	for _, c := range append(ColorPalette(include), candidates...) {
+		bc, ok := c.(BrickColor)
+		if !ok {
+			return nil, fmt.Errorf("candidate %v is not a BrickColor", c)
+		}
+		if _, seen := index[bc]; !seen {
+			index[bc] = len(colors)
+			colors = append(colors, bc)
+		}
+	}
+	if k < len(include) {
+		return nil, fmt.Errorf("cannot choose %d colors when %d must be included", k, len(include))
+	}
+	if k > len(colors) {
+		return nil, fmt.Errorf("cannot choose %d colors out of %d", k, len(colors))
+	}
+
+	// Cells of the same color only need to be compared once.
+	weight := make(map[color.RGBA]float64)
+	var cells []color.RGBA
+	ideal := &BrickImage{img: img, rows: rows, cols: cols, space: opts.Posterize.Space, reducer: opts.Posterize.Reducer}
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if x0, y0, x1, y1 := ideal.cellBounds(row, col); opts.Posterize.Mask.covers(img, x0, y0, x1, y1, opts.Posterize.Distance) {
+				continue
+			}
+			c := color.RGBAModel.Convert(ideal.cellColor(row, col)).(color.RGBA)
+			if weight[c] == 0 {
+				cells = append(cells, c)
+			}
+			weight[c]++
+		}
+	}
+	distances := make([][]float64, len(cells))
+	for i, cell := range cells {
+		distances[i] = make([]float64, len(colors))
+		for j, c := range colors {
+			distances[i][j] = weight[cell] * opts.Posterize.Distance.Distance(cell, c)
+		}
+	}
+	// paletteError is the error of the palette made of the colors at the indexes.
+	paletteError := func(chosen []int) float64 {
+		total := 0.0
+		for i := range cells {
+			best := math.Inf(1)
+			for _, j := range chosen {
+				best = math.Min(best, distances[i][j])
+			}
+			total += best
+		}
+		return total
+	}
+
+	var chosen []int
+	in := make([]bool, len(colors))
+	for _, c := range include {
+		chosen = append(chosen, index[c])
+		in[index[c]] = true
+	}
+	for len(chosen) < k {
+		best, bestError := -1, math.Inf(1)
+		for j := range colors {
+			if in[j] {
+				continue
+			}
+			if e := paletteError(append(chosen, j)); e < bestError {
+				best, bestError = j, e
+			}
+		}
+		chosen = append(chosen, best)
+		in[best] = true
+	}
+	current := paletteError(chosen)
+	for improved := true; improved; {
+		improved = false
+		for i := len(include); i < len(chosen); i++ {
+			for j := range colors {
+				if in[j] {
+					continue
+				}
+				old := chosen[i]
+				chosen[i] = j
+				if e := paletteError(chosen); e < current-1e-9 {
+					in[old], in[j] = false, true
+					current, improved = e, true
+				} else {
+					chosen[i] = old
+				}
+			}
+		}
+	}
+
+	result := include
+	for j, c := range colors {
+		if in[j] && !included(include, c) {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func included(colors []BrickColor, c BrickColor) bool {
+	for _, ic := range colors {
+		if ic == c {
+			return true
+		}
+	}
+	return false
+}
+
+// PaletteString returns the names of the colors in the form the --palette flag accepts.
+func PaletteString(colors []BrickColor) string {
+	var names []string
+	for _, c := range colors {
+		names = append(names, c.name)
+	}
+	return strings.Join(names, ",")
+}
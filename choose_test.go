@@ -0,0 +1,67 @@
+package BrickMosaic
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+// bands returns an image of vertical bands of the colors, each one pixel wide and as many pixels
+// as its count.
+func bands(height int, colors []color.Color, counts []int) image.Image {
+	width := 0
+	for _, n := range counts {
+		width += n
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	x := 0
+	for i, c := range colors {
+		for n := 0; n < counts[i]; n++ {
+			for y := 0; y < height; y++ {
+				img.Set(x, y, c)
+			}
+			x++
+		}
+	}
+	return img
+}
+
+func TestChoosePalette(t *testing.T) {
+	img := bands(2, []color.Color{BrightRed, BrightBlue, White, Black}, []int{4, 3, 2, 1})
+	candidates := color.Palette{Black, White, BrightRed, BrightBlue, BrightYellow, DarkGreen}
+	for _, test := range []struct {
+		name    string
+		k       int
+		include []BrickColor
+		want    []BrickColor
+	}{
+		{"most common colors", 2, nil, []BrickColor{BrightRed, BrightBlue}},
+		{"every color", 4, nil, []BrickColor{Black, White, BrightRed, BrightBlue}},
+		{"included first", 2, []BrickColor{BrightYellow}, []BrickColor{BrightYellow, BrightRed}},
+	} {
+		got, err := ChoosePalette(img, 1, 10, test.k, PaletteOptions{
+			Candidates: candidates,
+			Include:    test.include,
+			Posterize:  PosterizeOptions{Distance: CIEDE2000},
+		})
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, PaletteString(test.want), PaletteString(got))
+		}
+	}
+
+	for _, k := range []int{0, 7} {
+		if _, err := ChoosePalette(img, 1, 10, k, PaletteOptions{Candidates: candidates, Include: []BrickColor{Black}}); err == nil {
+			t.Errorf("for %d colors wanted an error", k)
+		}
+	}
+}
+
+func TestPaletteString(t *testing.T) {
+	if got, want := PaletteString([]BrickColor{Black, BrightRed}), "Black,BrightRed"; got != want {
+		t.Errorf("wanted %q got %q", want, got)
+	}
+}
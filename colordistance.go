@@ -0,0 +1,273 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+)
+
+// Matching a color to the nearest brick color needs a measure of how far apart two colors are.
+// Straight line distance between RGB values is cheap, but does not match how different colors
+// look: it treats a step in blue the same as a step in green, so dark blues and skin tones end up
+// matched to the wrong bricks. The CIE metrics measure distance in the CIELAB color space, which
+// was designed so that equal distances look about equally different. CIE76 is the straight line
+// distance in CIELAB; CIE94 and CIEDE2000 correct it for how much less we notice changes in
+// saturated colors, and CIEDE2000 also for blues and greys.
+//
+// See http://en.wikipedia.org/wiki/Color_difference.
+
+// ColorDistance is a measure of how different two colors look.
+type ColorDistance int
+
+const (
+	// RGBDistance is the straight line distance between the 8 bit RGBA values, as used by
+	// color.Palette.Convert.
+	RGBDistance ColorDistance = iota
+	// CIE76 is the straight line distance in CIELAB.
+	CIE76
+	// CIE94 is the 1994 CIE color difference, with the weights for graphic arts.
+	CIE94
+	// CIEDE2000 is the 2000 CIE color difference.
+	CIEDE2000
+)
+
+func (d ColorDistance) String() string {
+	switch d {
+	case RGBDistance:
+		return "rgb"
+	case CIE76:
+		return "cie76"
+	case CIE94:
+		return "cie94"
+	case CIEDE2000:
+		return "ciede2000"
+	}
+	return fmt.Sprintf("ColorDistance(%d)", int(d))
+}
+
+// ColorDistanceForName returns the color distance whose String matches name.
+func ColorDistanceForName(name string) (ColorDistance, error) {
+	for _, d := range []ColorDistance{RGBDistance, CIE76, CIE94, CIEDE2000} {
+		if d.String() == name {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown color distance %q; wanted one of rgb, cie76, cie94 or ciede2000", name)
+}
+
+// Distance returns how different c1 and c2 look; 0 if they are the same.
+func (d ColorDistance) Distance(c1, c2 color.Color) float64 {
+	if d == RGBDistance {
+		r1, g1, b1, a1 := c1.RGBA()
+		r2, g2, b2, a2 := c2.RGBA()
+		dr := float64(r1>>8) - float64(r2>>8)
+		dg := float64(g1>>8) - float64(g2>>8)
+		db := float64(b1>>8) - float64(b2>>8)
+		da := float64(a1>>8) - float64(a2>>8)
+		return math.Sqrt(dr*dr + dg*dg + db*db + da*da)
+	}
+	return d.labDistance(toLab(c1), toLab(c2))
+}
+
+func (d ColorDistance) labDistance(l1, l2 lab) float64 {
+	switch d {
+	case CIE94:
+		return cie94(l1, l2)
+	case CIEDE2000:
+		return ciede2000(l1, l2)
+	}
+	return cie76(l1, l2)
+}
+
+// Nearest returns the color of the palette that is least distant from c. Ties go to the color
+// that comes first in the palette.
+func (d ColorDistance) Nearest(p color.Palette, c color.Color) color.Color {
+	if len(p) == 0 {
+		return nil
+	}
+	best, bestDistance := p[0], math.Inf(1)
+	if d == RGBDistance {
+		for _, pc := range p {
+			if dist := d.Distance(c, pc); dist < bestDistance {
+				best, bestDistance = pc, dist
+			}
+		}
+		return best
+	}
+	// Only convert c to CIELAB once.
+	target := toLab(c)
+	for _, pc := range p {
+		if dist := d.labDistance(target, toLab(pc)); dist < bestDistance {
+			best, bestDistance = pc, dist
+		}
+	}
+	return best
+}
+
+// lab is a color in the CIELAB color space, relative to the D65 white point.
+type lab struct {
+	L, A, B float64
+}
+
+// The D65 white point in CIEXYZ.
+const (
+	whiteX = 0.95047
+	whiteY = 1.0
+	whiteZ = 1.08883
+)
+
+// toLab converts an sRGB color to CIELAB.
+func toLab(c color.Color) lab {
+	r, g, b, _ := c.RGBA()
+	lr, lg, lb := linearize(r), linearize(g), linearize(b)
+	x := 0.4124564*lr + 0.3575761*lg + 0.1804375*lb
+	y := 0.2126729*lr + 0.7151522*lg + 0.0721750*lb
+	z := 0.0193339*lr + 0.1191920*lg + 0.9503041*lb
+	fx, fy, fz := labF(x/whiteX), labF(y/whiteY), labF(z/whiteZ)
+	return lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// rgba converts the color back to sRGB. Colors outside of sRGB are clamped to it.
+func (l lab) rgba() color.RGBA {
+	fy := (l.L + 16) / 116
+	fx := fy + l.A/500
+	fz := fy - l.B/200
+	x, y, z := whiteX*labFInverse(fx), whiteY*labFInverse(fy), whiteZ*labFInverse(fz)
+	r := 3.2404542*x - 1.5371385*y - 0.4985314*z
+	g := -0.9692660*x + 1.8760108*y + 0.0415560*z
+	b := 0.0556434*x - 0.2040259*y + 1.0572252*z
+	return color.RGBA{R: delinearize(r), G: delinearize(g), B: delinearize(b), A: uint8(255)}
+}
+
+// linearize converts a 16 bit sRGB channel to linear light in [0, 1].
+func linearize(v uint32) float64 {
+	c := float64(v) / 65535
+	if c <= 0.04045 {
+		return c / 12.92
+	}
+	return math.Pow((c+0.055)/1.055, 2.4)
+}
+
+// delinearize converts linear light to an 8 bit sRGB channel.
+func delinearize(c float64) uint8 {
+	if c <= 0.0031308 {
+		c *= 12.92
+	} else {
+		c = 1.055*math.Pow(c, 1/2.4) - 0.055
+	}
+	return uint8(math.Max(0, math.Min(255, math.Floor(c*255+0.5))))
+}
+
+const labEpsilon = 6.0 / 29
+
+func labF(t float64) float64 {
+	if t > labEpsilon*labEpsilon*labEpsilon {
+		return math.Cbrt(t)
+	}
+	return t/(3*labEpsilon*labEpsilon) + 4.0/29
+}
+
+func labFInverse(t float64) float64 {
+	if t > labEpsilon {
+		return t * t * t
+	}
+	return 3 * labEpsilon * labEpsilon * (t - 4.0/29)
+}
+
+func cie76(l1, l2 lab) float64 {
+	dl, da, db := l1.L-l2.L, l1.A-l2.A, l1.B-l2.B
+	return math.Sqrt(dl*dl + da*da + db*db)
+}
+
+func cie94(l1, l2 lab) float64 {
+	const k1, k2 = 0.045, 0.015
+	c1 := math.Hypot(l1.A, l1.B)
+	c2 := math.Hypot(l2.A, l2.B)
+	dl, dc := l1.L-l2.L, c1-c2
+	da, db := l1.A-l2.A, l1.B-l2.B
+	dh2 := da*da + db*db - dc*dc
+	if dh2 < 0 {
+		dh2 = 0
+	}
+	sc, sh := 1+k1*c1, 1+k2*c1
+	return math.Sqrt(dl*dl + (dc/sc)*(dc/sc) + dh2/(sh*sh))
+}
+
+// ciede2000 follows Sharma, Wu and Dalal, "The CIEDE2000 Color-Difference Formula:
+// Implementation Notes, Supplementary Test Data, and Mathematical Observations" (2005).
+func ciede2000(l1, l2 lab) float64 {
+	rad := math.Pi / 180
+	cBar := (math.Hypot(l1.A, l1.B) + math.Hypot(l2.A, l2.B)) / 2
+	cBar7 := math.Pow(cBar, 7)
+	g := 0.5 * (1 - math.Sqrt(cBar7/(cBar7+math.Pow(25, 7))))
+	a1, a2 := (1+g)*l1.A, (1+g)*l2.A
+	c1, c2 := math.Hypot(a1, l1.B), math.Hypot(a2, l2.B)
+	h1, h2 := hueAngle(a1, l1.B), hueAngle(a2, l2.B)
+
+	dL := l2.L - l1.L
+	dC := c2 - c1
+	var dh float64
+	if c1*c2 != 0 {
+		dh = h2 - h1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+	}
+	dH := 2 * math.Sqrt(c1*c2) * math.Sin(dh/2*rad)
+
+	lBar := (l1.L + l2.L) / 2
+	cBarPrime := (c1 + c2) / 2
+	hBar := h1 + h2
+	if c1*c2 != 0 {
+		if math.Abs(h1-h2) <= 180 {
+			hBar /= 2
+		} else if h1+h2 < 360 {
+			hBar = (hBar + 360) / 2
+		} else {
+			hBar = (hBar - 360) / 2
+		}
+	}
+	t := 1 - 0.17*math.Cos((hBar-30)*rad) + 0.24*math.Cos(2*hBar*rad) +
+		0.32*math.Cos((3*hBar+6)*rad) - 0.20*math.Cos((4*hBar-63)*rad)
+	dTheta := 30 * math.Exp(-((hBar-275)/25)*((hBar-275)/25))
+	cBarPrime7 := math.Pow(cBarPrime, 7)
+	rc := 2 * math.Sqrt(cBarPrime7/(cBarPrime7+math.Pow(25, 7)))
+	sl := 1 + 0.015*(lBar-50)*(lBar-50)/math.Sqrt(20+(lBar-50)*(lBar-50))
+	sc := 1 + 0.045*cBarPrime
+	sh := 1 + 0.015*cBarPrime*t
+	rt := -math.Sin(2*dTheta*rad) * rc
+
+	return math.Sqrt((dL/sl)*(dL/sl) + (dC/sc)*(dC/sc) + (dH/sh)*(dH/sh) + rt*(dC/sc)*(dH/sh))
+}
+
+// hueAngle returns the angle of (a, b) in degrees, in [0, 360).
+func hueAngle(a, b float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// labError is the quantization error between two colors in CIELAB, for dithering with the CIE
+// metrics.
+type labError struct {
+	L, A, B float64
+}
+
+func newLabError(oldC, newC color.Color) labError {
+	o, n := toLab(oldC), toLab(newC)
+	return labError{o.L - n.L, o.A - n.A, o.B - n.B}
+}
+
+// addTo adds the given fraction of the error to c.
+func (e labError) addTo(c color.Color, factor float32) color.Color {
+	l := toLab(c)
+	f := float64(factor)
+	return lab{l.L + e.L*f, l.A + e.A*f, l.B + e.B*f}.rgba()
+}
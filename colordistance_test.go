@@ -0,0 +1,98 @@
+package BrickMosaic
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestCIEDE2000(t *testing.T) {
+	// Pairs from Sharma, Wu and Dalal's supplementary test data.
+	for _, test := range []struct {
+		l1, l2 lab
+		want   float64
+	}{
+		{lab{50, 2.6772, -79.7751}, lab{50, 0, -82.7485}, 2.0425},
+		{lab{50, -1.3802, -84.2814}, lab{50, 0, -82.7485}, 1.0000},
+		{lab{50, 2.5, 0}, lab{50, 0, -2.5}, 4.3065},
+		{lab{50, 2.5, 0}, lab{73, 25, -18}, 27.1492},
+		{lab{60.2574, -34.0099, 36.2677}, lab{60.4626, -34.1751, 39.4387}, 1.2644},
+		{lab{22.7233, 20.0904, -46.694}, lab{23.0331, 14.973, -42.5619}, 2.0373},
+	} {
+		if got := ciede2000(test.l1, test.l2); math.Abs(got-test.want) > 1e-4 {
+			t.Errorf("for %v and %v wanted %v got %v", test.l1, test.l2, test.want, got)
+		}
+		if got := ciede2000(test.l2, test.l1); math.Abs(got-test.want) > 1e-4 {
+			t.Errorf("for %v and %v wanted %v got %v", test.l2, test.l1, test.want, got)
+		}
+	}
+}
+
+func TestColorDistances(t *testing.T) {
+	for _, test := range []struct {
+		d      ColorDistance
+		c1, c2 color.Color
+		want   float64
+	}{
+		{RGBDistance, color.RGBA{0, 0, 0, 255}, color.RGBA{3, 4, 0, 255}, 5},
+		{CIE76, color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}, 100},
+		{CIE94, color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}, 100},
+		{CIEDE2000, White, White, 0},
+	} {
+		if got := test.d.Distance(test.c1, test.c2); math.Abs(got-test.want) > 1e-3 {
+			t.Errorf("for %v between %v and %v wanted %v got %v", test.d, test.c1, test.c2, test.want, got)
+		}
+	}
+}
+
+func TestLabRoundTrip(t *testing.T) {
+	for _, c := range []color.RGBA{{0, 0, 0, 255}, {255, 255, 255, 255}, {13, 105, 171, 255}, {204, 142, 104, 255}} {
+		if got := toLab(c).rgba(); got != c {
+			t.Errorf("for %v got %v", c, got)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	palette := color.Palette{Black, White, BrightBlue, DarkGreen}
+	// A dark green is nearer to the black brick than to the green one in RGB, but looks more like
+	// the green.
+	green := color.RGBA{0, 96, 0, 255}
+	for _, test := range []struct {
+		d    ColorDistance
+		want color.Color
+	}{
+		{RGBDistance, Black},
+		{CIE76, DarkGreen},
+		{CIE94, DarkGreen},
+		{CIEDE2000, DarkGreen},
+	} {
+		if got := test.d.Nearest(palette, green); got != test.want {
+			t.Errorf("for %v wanted %v got %v", test.d, test.want, got)
+		}
+	}
+	if got := RGBDistance.Nearest(palette, green); got != palette.Convert(green) {
+		t.Errorf("wanted rgb to match color.Palette.Convert %v got %v", palette.Convert(green), got)
+	}
+
+	img := NewUniform(green, image.Rect(0, 0, 10, 10))
+	ideal := PosterizeWith(img, palette, 2, 2, StudsOut, PosterizeOptions{Distance: CIEDE2000})
+	if got := ideal.Color(0, 0); got != DarkGreen {
+		t.Errorf("wanted the posterized green to be %v got %v", DarkGreen, got)
+	}
+	if got, want := ideal.MeanColorDistance(), CIEDE2000.Distance(green, DarkGreen); math.Abs(got-want) > 1e-9 {
+		t.Errorf("wanted a mean color distance of %v got %v", want, got)
+	}
+}
+
+func TestColorDistanceForName(t *testing.T) {
+	for _, d := range []ColorDistance{RGBDistance, CIE76, CIE94, CIEDE2000} {
+		if got, err := ColorDistanceForName(d.String()); err != nil || got != d {
+			t.Errorf("for %v got %v, %v", d, got, err)
+		}
+	}
+	if _, err := ColorDistanceForName("hsv"); err == nil {
+		t.Errorf("wanted an error for an unknown color distance")
+	}
+}
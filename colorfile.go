@@ -0,0 +1,192 @@
+package BrickMosaic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Palettes can be read from local copies of the color lists published by LDraw, BrickLink and
+// Rebrickable, instead of the colors in this package:
+//
+// LDConfig.ldr, from LDraw, has a line per color:
+//
+//	0 !COLOUR Bright_Green  CODE  10  VALUE #4B9F4A  EDGE #333333
+//
+// The BrickLink colors export is tab separated, and its first line names the columns; the
+// "Color ID", "Color Name" and "RGB" columns are used:
+//
+//	Color ID	Color Name	RGB	Type	...
+//	11	Black	212121	Solid	...
+//
+// Rebrickable's colors.csv is comma separated, and its first line also names the columns; the
+// "id", "name" and "rgb" columns are used:
+//
+//	id,name,rgb,is_trans
+//	0,Black,05131D,f
+//
+// Names are turned into the style of this package by dropping spaces, underscores and dashes
+// and capitalizing each word, so "Light Bluish Gray" is LightBluishGray. Ids are those of the
+// file the color came from. Placeholder colors such as Rebrickable's "[Unknown]" are skipped.
+
+// LoadPalette reads the colors in the file at path and makes them known to ColorForName. Files
+// ending in .ldr are read as LDConfig.ldr; otherwise the header tells BrickLink and Rebrickable
+// files apart.
+func LoadPalette(path string) ([]BrickColor, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var colors []BrickColor
+	firstLine := strings.ToLower(strings.SplitN(string(data), "\n", 2)[0])
+	switch {
+	case strings.ToLower(filepath.Ext(path)) == ".ldr":
+		colors, err = ParseLDConfig(bytes.NewReader(data))
+	case strings.Contains(firstLine, "color id"):
+		colors, err = ParseBrickLinkColors(bytes.NewReader(data))
+	case strings.HasPrefix(firstLine, "id,"):
+		colors, err = ParseRebrickableColors(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("%v is not an LDConfig.ldr, BrickLink or Rebrickable color file", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+	RegisterColors(colors)
+	return colors, nil
+}
+
+// ParseLDConfig reads the colors from LDraw's LDConfig.ldr. Lines other than !COLOUR
+// definitions are ignored.
+func ParseLDConfig(r io.Reader) ([]BrickColor, error) {
+	var colors []BrickColor
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[0] != "0" || fields[1] != "!COLOUR" {
+			continue
+		}
+		c := BrickColor{name: colorName(fields[2])}
+		var code, value string
+		for i := 3; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "CODE":
+				code = fields[i+1]
+			case "VALUE":
+				value = fields[i+1]
+			}
+		}
+		id, err := strconv.Atoi(code)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid code %q", line, code)
+		}
+		rgb, err := parseRGB(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		c.id, c.c = id, rgb
+		colors = append(colors, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return colors, nil
+}
+
+// ParseBrickLinkColors reads the colors from a BrickLink colors export.
+func ParseBrickLinkColors(r io.Reader) ([]BrickColor, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = '\t'
+	reader.LazyQuotes = true
+	return parseColorTable(reader, "color id", "color name", "rgb")
+}
+
+// ParseRebrickableColors reads the colors from Rebrickable's colors.csv.
+func ParseRebrickableColors(r io.Reader) ([]BrickColor, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	return parseColorTable(reader, "id", "name", "rgb")
+}
+
+// parseColorTable reads colors from the records of the reader, whose first record names the
+// columns. The id, name and rgb columns are used and any others are ignored.
+func parseColorTable(reader *csv.Reader, idColumn, nameColumn, rgbColumn string) ([]BrickColor, error) {
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("color file is empty")
+	}
+	column := make(map[string]int)
+	for i, name := range records[0] {
+		column[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{idColumn, nameColumn, rgbColumn} {
+		if _, ok := column[name]; !ok {
+			return nil, fmt.Errorf("color file is missing the %q column", name)
+		}
+	}
+
+	var colors []BrickColor
+	for i, record := range records[1:] {
+		if len(record) != len(records[0]) {
+			return nil, fmt.Errorf("line %d: wanted %d fields got %d", i+2, len(records[0]), len(record))
+		}
+		name := strings.TrimSpace(record[column[nameColumn]])
+		if strings.HasPrefix(name, "[") || strings.HasPrefix(name, "(") {
+			continue
+		}
+		id, err := strconv.Atoi(strings.TrimSpace(record[column[idColumn]]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid id %q", i+2, record[column[idColumn]])
+		}
+		rgb, err := parseRGB(record[column[rgbColumn]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+2, err)
+		}
+		colors = append(colors, BrickColor{id: id, name: colorName(name), c: rgb})
+	}
+	return colors, nil
+}
+
+// parseRGB converts a hex color such as "#4B9F4A" or "4B9F4A" to an opaque color.
+func parseRGB(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: uint8(255)}, nil
+}
+
+// colorName turns a color's name in a file into the style of this package, e.g. "Light Bluish
+// Gray", "Light_Bluish_Grey" and "Trans-Clear" become LightBluishGray, LightBluishGrey and
+// TransClear.
+func colorName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '_' || r == '-'
+	})
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, "")
+}
+
+// ColorPalette returns a palette of the colors, e.g. for posterizing an image.
+func ColorPalette(colors []BrickColor) color.Palette {
+	palette := make(color.Palette, len(colors))
+	for i, c := range colors {
+		palette[i] = c
+	}
+	return palette
+}
@@ -0,0 +1,140 @@
+package BrickMosaic
+
+import (
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	ldConfig = `0 LDraw.org Configuration File
+0 // Solid Colours
+0 !COLOUR Black                CODE   0   VALUE #1B2A34   EDGE #2B4354
+0 !COLOUR Light_Bluish_Grey    CODE  71   VALUE #A0A5A9   EDGE #333333
+0 // Transparent Colours
+0 !COLOUR Trans_Clear          CODE  47   VALUE #FCFCFC   EDGE #C3C3C3   ALPHA 128
+`
+	brickLinkColors = "Color ID\tColor Name\tRGB\tType\tParts\n" +
+		"0\t(Not Applicable)\t\tN/A\t0\n" +
+		"11\tBlack\t212121\tSolid\t12000\n" +
+		"86\tLight Bluish Gray\tA0A5A9\tSolid\t9000\n"
+	rebrickableColors = `id,name,rgb,is_trans
+-1,[Unknown],0033B2,f
+0,Black,05131D,f
+71,Light Bluish Gray,A0A5A9,f
+47,Trans-Clear,FCFCFC,t
+`
+)
+
+func rgb(r, g, b uint8) color.RGBA {
+	return color.RGBA{R: r, G: g, B: b, A: uint8(255)}
+}
+
+func TestParseColorFiles(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		parse func(r *strings.Reader) ([]BrickColor, error)
+		input string
+		want  []BrickColor
+	}{
+		{
+			"LDConfig",
+			func(r *strings.Reader) ([]BrickColor, error) { return ParseLDConfig(r) },
+			ldConfig,
+			[]BrickColor{
+				{id: 0, name: "Black", c: rgb(0x1B, 0x2A, 0x34)},
+				{id: 71, name: "LightBluishGrey", c: rgb(0xA0, 0xA5, 0xA9)},
+				{id: 47, name: "TransClear", c: rgb(0xFC, 0xFC, 0xFC)},
+			},
+		},
+		{
+			"BrickLink",
+			func(r *strings.Reader) ([]BrickColor, error) { return ParseBrickLinkColors(r) },
+			brickLinkColors,
+			[]BrickColor{
+				{id: 11, name: "Black", c: rgb(0x21, 0x21, 0x21)},
+				{id: 86, name: "LightBluishGray", c: rgb(0xA0, 0xA5, 0xA9)},
+			},
+		},
+		{
+			"Rebrickable",
+			func(r *strings.Reader) ([]BrickColor, error) { return ParseRebrickableColors(r) },
+			rebrickableColors,
+			[]BrickColor{
+				{id: 0, name: "Black", c: rgb(0x05, 0x13, 0x1D)},
+				{id: 71, name: "LightBluishGray", c: rgb(0xA0, 0xA5, 0xA9)},
+				{id: 47, name: "TransClear", c: rgb(0xFC, 0xFC, 0xFC)},
+			},
+		},
+	} {
+		got, err := test.parse(strings.NewReader(test.input))
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestParseColorFileErrors(t *testing.T) {
+	for _, input := range []string{
+		"id,name\n0,Black\n",
+		"id,name,rgb\nzero,Black,05131D\n",
+		"id,name,rgb\n0,Black,blue\n",
+	} {
+		if _, err := ParseRebrickableColors(strings.NewReader(input)); err == nil {
+			t.Errorf("for %q wanted an error", input)
+		}
+	}
+}
+
+func TestLoadPalette(t *testing.T) {
+	// Loading registers the colors; put the standard ones back afterwards.
+	defer func() {
+		nameMap = buildNameMap()
+	}()
+	dir, err := ioutil.TempDir("", "palette")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		file, contents string
+		want           int
+	}{
+		{"LDConfig.ldr", ldConfig, 3},
+		{"colors.txt", brickLinkColors, 2},
+		{"colors.csv", rebrickableColors, 3},
+	} {
+		path := filepath.Join(dir, test.file)
+		if err := ioutil.WriteFile(path, []byte(test.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		colors, err := LoadPalette(path)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.file, err)
+		}
+		if len(colors) != test.want || len(ColorPalette(colors)) != test.want {
+			t.Errorf("for %q wanted %d colors got %d", test.file, test.want, len(colors))
+		}
+		for _, c := range colors {
+			if got := ColorForName(c.name); got == nil || *got != c {
+				t.Errorf("for %q wanted %v to be registered got %v", test.file, c, got)
+			}
+		}
+	}
+	if c := ColorForName("Light Bluish Gray"); c == nil || c.name != "LightBluishGray" {
+		t.Errorf("wanted Light Bluish Gray to match LightBluishGray got %v", c)
+	}
+	path := filepath.Join(dir, "unknown.txt")
+	ioutil.WriteFile(path, []byte("hello\n"), 0644)
+	if _, err := LoadPalette(path); err == nil {
+		t.Errorf("wanted an error for an unknown format")
+	}
+}
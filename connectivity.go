@@ -0,0 +1,295 @@
+package BrickMosaic
+
+import (
+	"sort"
+)
+
+// Connectivity analysis determines whether a Plan holds together as a single piece once it is
+// built. Two bricks are connected when the studs of one are pressed into the underside of the
+// other. Which faces carry studs depends on the ViewOrientation:
+//
+// StudsTop: the studs face up, so a brick is connected to the bricks directly above and below it
+// in the grid.
+//
+// StudsRight: the studs face right, so a brick is connected to the bricks directly to its left and
+// right in the grid.
+//
+// StudsOut: the studs face the viewer, so bricks side by side in the mosaic are not connected to
+// each other at all; a StudsOut mosaic only holds together through the plates it sits on.
+//
+// The other layers of a LayeredPlan count too. The frame is built in the same plane as the mosaic.
+// Behind that plane come the backing and then, for StudsOut, the baseplates; each piece is held by
+// the pieces of the next plane back that it lies on, by one stud for every cell they share. So a
+// StudsOut mosaic on unbonded baseplates comes apart into one piece per baseplate, and a backing
+// holds together the wall pieces it spans.
+//
+// Plates and bricks differ only in how many rows (or columns) of the grid they span, which is
+// already part of their extent, so a plate wedged between two bricks connects to both of them.
+// The strength of a connection is the number of studs involved: the number of grid cells the two
+// bricks share along their common face, times the depth that both bricks have in common.
+
+// Connection is a stud connection between two bricks in the plan.
+type Connection struct {
+	A, B PlacedBrick
+	// Studs is the number of studs that hold A and B together.
+	Studs int
+}
+
+// ConnectivityReport describes how a plan holds together.
+type ConnectivityReport struct {
+	// Components are the groups of bricks that hold together, largest first. A plan that holds
+	// together as one piece has exactly one component.
+	Components [][]PlacedBrick
+	// Articulations are the bricks whose removal would split their component in two.
+	Articulations []PlacedBrick
+	// WeakSeams are the connections whose failure would split their component in two, weakest
+	// (fewest studs) first.
+	WeakSeams []Connection
+}
+
+// Connected determines whether the plan holds together as a single piece.
+func (r ConnectivityReport) Connected() bool {
+	return len(r.Components) <= 1
+}
+
+// Islands returns every component other than the largest; these are the parts of the plan that
+// will fall off.
+func (r ConnectivityReport) Islands() [][]PlacedBrick {
+	if len(r.Components) <= 1 {
+		return nil
+	}
+	return r.Components[1:]
+}
+
+// studGraph is the graph of stud connections between the bricks of a plan. Bricks are numbered
+// by their position in the bricks slice.
+type studGraph struct {
+	bricks []PlacedBrick
+	// edges[i] maps each neighbor of brick i to the number of studs connecting them.
+	edges []map[int]int
+}
+
+// AnalyzeConnectivity builds the stud connection graph of the plan and reports its connected
+// components, articulation bricks and weakest seams.
+func AnalyzeConnectivity(p Plan) ConnectivityReport {
+	g := newStudGraph(studPlanes(p), p.Orig().Orientation())
+	var report ConnectivityReport
+
+	component := make([]int, len(g.bricks))
+	for i := range component {
+		component[i] = -1
+	}
+	for i := range g.bricks {
+		if component[i] != -1 {
+			continue
+		}
+		id := len(report.Components)
+		var members []PlacedBrick
+		stack := []int{i}
+		component[i] = id
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			members = append(members, g.bricks[n])
+			for _, m := range g.neighbors(n) {
+				if component[m] == -1 {
+					component[m] = id
+					stack = append(stack, m)
+				}
+			}
+		}
+		sort.Sort(placedRowMajor(members))
+		report.Components = append(report.Components, members)
+	}
+	sort.Stable(bySize(report.Components))
+
+	articulations, bridges := g.cuts()
+	for _, i := range articulations {
+		report.Articulations = append(report.Articulations, g.bricks[i])
+	}
+	for _, b := range bridges {
+		report.WeakSeams = append(report.WeakSeams, Connection{g.bricks[b[0]], g.bricks[b[1]], g.edges[b[0]][b[1]]})
+	}
+	sort.Stable(byStuds(report.WeakSeams))
+	return report
+}
+
+// studPlanes returns the pieces of the plan in the planes they are built in, front to back: the
+// mosaic and its frame, then the backing and the baseplates of a LayeredPlan, if it has them.
+func studPlanes(p Plan) [][]PlacedBrick {
+	front := make([]PlacedBrick, len(p.Pieces()))
+	copy(front, p.Pieces())
+	layered, ok := p.(LayeredPlan)
+	if !ok {
+		return [][]PlacedBrick{front}
+	}
+	planes := [][]PlacedBrick{append(front, layered.Layer(FrameLayer)...)}
+	for _, l := range []Layer{BackingLayer, BaseplateLayer} {
+		if bricks := layered.Layer(l); len(bricks) > 0 {
+			planes = append(planes, bricks)
+		}
+	}
+	return planes
+}
+
+func newStudGraph(planes [][]PlacedBrick, o ViewOrientation) *studGraph {
+	g := &studGraph{}
+	// plane[i] is the plane brick i is in, and owners[k] the brick covering each cell of plane k.
+	var plane []int
+	owners := make([]map[Location]int, len(planes))
+	for k, pieces := range planes {
+		bricks := make([]PlacedBrick, len(pieces))
+		copy(bricks, pieces)
+		sort.Sort(placedRowMajor(bricks))
+		owners[k] = make(map[Location]int)
+		for _, b := range bricks {
+			i := len(g.bricks)
+			g.bricks = append(g.bricks, b)
+			g.edges = append(g.edges, make(map[int]int))
+			plane = append(plane, k)
+			for _, rel := range b.Extent() {
+				owners[k][b.Origin.Add(rel)] = i
+			}
+		}
+	}
+	// Direction from a cell to the cell its studs press into, within a plane.
+	var studs Location
+	switch o {
+	case StudsTop:
+		studs = Location{Row: -1}
+	case StudsRight:
+		studs = Location{Col: 1}
+	}
+	for i, b := range g.bricks {
+		for _, rel := range b.Extent() {
+			loc := b.Origin.Add(rel)
+			if o != StudsOut {
+				if j, ok := owners[plane[i]][loc.Add(studs)]; ok && j != i {
+					depth := b.Shape.Width()
+					if w := g.bricks[j].Shape.Width(); w < depth {
+						depth = w
+					}
+					g.connect(i, j, depth)
+				}
+			}
+			if k := plane[i] + 1; k < len(planes) {
+				if j, ok := owners[k][loc]; ok {
+					g.connect(i, j, 1)
+				}
+			}
+		}
+	}
+	return g
+}
+
+// connect adds studs to the connection between bricks i and j.
+func (g *studGraph) connect(i, j, studs int) {
+	g.edges[i][j] += studs
+	g.edges[j][i] += studs
+}
+
+// neighbors returns the bricks connected to brick n, in ascending order.
+func (g *studGraph) neighbors(n int) []int {
+	result := make([]int, 0, len(g.edges[n]))
+	for m := range g.edges[n] {
+		result = append(result, m)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// cuts finds the articulation points and bridges of the graph using Tarjan's algorithm.
+func (g *studGraph) cuts() (articulations []int, bridges [][2]int) {
+	n := len(g.bricks)
+	disc := make([]int, n)
+	low := make([]int, n)
+	isArticulation := make([]bool, n)
+	counter := 0
+
+	var visit func(u, parent int)
+	visit = func(u, parent int) {
+		counter++
+		disc[u] = counter
+		low[u] = counter
+		children := 0
+		for _, v := range g.neighbors(u) {
+			if disc[v] == 0 {
+				children++
+				visit(v, u)
+				if low[v] < low[u] {
+					low[u] = low[v]
+				}
+				if parent != -1 && low[v] >= disc[u] {
+					isArticulation[u] = true
+				}
+				if low[v] > disc[u] {
+					bridges = append(bridges, [2]int{u, v})
+				}
+			} else if v != parent && disc[v] < low[u] {
+				low[u] = disc[v]
+			}
+		}
+		if parent == -1 && children > 1 {
+			isArticulation[u] = true
+		}
+	}
+	for u := 0; u < n; u++ {
+		if disc[u] == 0 {
+			visit(u, -1)
+		}
+	}
+	for u, ok := range isArticulation {
+		if ok {
+			articulations = append(articulations, u)
+		}
+	}
+	return articulations, bridges
+}
+
+// placedRowMajor sorts bricks by their layer, then by their origin, top to bottom, left to right.
+type placedRowMajor []PlacedBrick
+
+func (p placedRowMajor) Len() int {
+	return len(p)
+}
+
+func (p placedRowMajor) Less(i, j int) bool {
+	if p[i].Layer != p[j].Layer {
+		return p[i].Layer < p[j].Layer
+	}
+	return rowMajor{p[i].Origin, p[j].Origin}.Less(0, 1)
+}
+
+func (p placedRowMajor) Swap(i, j int) {
+	p[i], p[j] = p[j], p[i]
+}
+
+// bySize sorts components, largest first.
+type bySize [][]PlacedBrick
+
+func (b bySize) Len() int {
+	return len(b)
+}
+
+func (b bySize) Less(i, j int) bool {
+	return len(b[i]) > len(b[j])
+}
+
+func (b bySize) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+// byStuds sorts connections, weakest first.
+type byStuds []Connection
+
+func (b byStuds) Len() int {
+	return len(b)
+}
+
+func (b byStuds) Less(i, j int) bool {
+	return b[i].Studs < b[j].Studs
+}
+
+func (b byStuds) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
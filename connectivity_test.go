@@ -0,0 +1,193 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"testing"
+)
+
+// uniformIdeal is an Ideal of a single color.
+type uniformIdeal struct {
+	o          ViewOrientation
+	rows, cols int
+	c          BrickColor
+}
+
+func (u uniformIdeal) Orientation() ViewOrientation {
+	return u.o
+}
+func (u uniformIdeal) NumRows() int {
+	return u.rows
+}
+func (u uniformIdeal) NumCols() int {
+	return u.cols
+}
+func (u uniformIdeal) Color(row, col int) BrickColor {
+	return u.c
+}
+
+// brickPlan is a Plan made up of the given bricks.
+type brickPlan struct {
+	ideal  Ideal
+	bricks []PlacedBrick
+}
+
+func (b brickPlan) Orig() Ideal {
+	return b.ideal
+}
+
+func (b brickPlan) Pieces() []PlacedBrick {
+	return b.bricks
+}
+
+func (b brickPlan) Piece(row, col int) PlacedBrick {
+	for _, p := range b.bricks {
+		if p.Origin == (Location{row, col}) {
+			return p
+		}
+	}
+	return PlacedBrick{}
+}
+
+func (b brickPlan) Inventory() Inventory {
+	i := MakeInventory()
+	for _, p := range b.bricks {
+		i.Add(p.Color, p.Shape)
+	}
+	return i
+}
+
+func (b brickPlan) Shortfall() Inventory {
+	return MakeInventory()
+}
+
+// placeBrick returns the brick placed at origin in the given orientation.
+func placeBrick(id int, o ViewOrientation, b Brick, origin Location) PlacedBrick {
+	piece := PiecesForOrientation(o, []Brick{b})[0]
+	return PlacedBrick{
+		Id:          id,
+		Origin:      origin,
+		Locs:        piece.Extent(),
+		Color:       BrightRed,
+		Shape:       piece,
+		Orientation: o,
+	}
+}
+
+func origins(bricks []PlacedBrick) []Location {
+	var result []Location
+	for _, b := range bricks {
+		result = append(result, b.Origin)
+	}
+	return result
+}
+
+func TestAnalyzeConnectivity(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		o             ViewOrientation
+		bricks        []PlacedBrick
+		components    [][]Location
+		articulations []Location
+		weakStuds     []int
+	}{
+		{
+			name: "running bond",
+			o:    StudsTop,
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsTop, OneByFour, Location{0, 2}),
+				placeBrick(2, StudsTop, OneByFour, Location{3, 0}),
+				placeBrick(3, StudsTop, OneByFour, Location{3, 4}),
+			},
+			components:    [][]Location{{{0, 2}, {3, 0}, {3, 4}}},
+			articulations: []Location{{0, 2}},
+			weakStuds:     []int{2, 2},
+		},
+		{
+			name: "stacked seams leave an island",
+			o:    StudsTop,
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsTop, OneByFour, Location{0, 0}),
+				placeBrick(2, StudsTop, OneByFour, Location{3, 0}),
+				placeBrick(3, StudsTop, OneByFour, Location{3, 4}),
+			},
+			components: [][]Location{{{0, 0}, {3, 0}}, {{3, 4}}},
+			weakStuds:  []int{4},
+		},
+		{
+			name: "2x4 on 1x4 connects with one row of studs",
+			o:    StudsTop,
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsTop, TwoByFour, Location{0, 0}),
+				placeBrick(2, StudsTop, OneByFour, Location{3, 0}),
+			},
+			components: [][]Location{{{0, 0}, {3, 0}}},
+			weakStuds:  []int{4},
+		},
+		{
+			name: "studs right connect left to right",
+			o:    StudsRight,
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsRight, OneByFour, Location{0, 0}),
+				placeBrick(2, StudsRight, OneByFourPlate, Location{2, 3}),
+			},
+			components: [][]Location{{{0, 0}, {2, 3}}},
+			weakStuds:  []int{2},
+		},
+		{
+			name: "studs out never connect",
+			o:    StudsOut,
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsOut, OneByFour, Location{0, 0}),
+				placeBrick(2, StudsOut, OneByFour, Location{1, 0}),
+			},
+			components: [][]Location{{{0, 0}}, {{1, 0}}},
+		},
+	} {
+		plan := brickPlan{uniformIdeal{test.o, 6, 8, BrightRed}, test.bricks}
+		got := AnalyzeConnectivity(plan)
+
+		var components [][]Location
+		for _, c := range got.Components {
+			components = append(components, origins(c))
+		}
+		if !reflect.DeepEqual(components, test.components) {
+			t.Errorf("for %q wanted components %v got %v", test.name, test.components, components)
+		}
+		if got.Connected() != (len(test.components) == 1) {
+			t.Errorf("for %q wanted connected %v", test.name, len(test.components) == 1)
+		}
+		if a := origins(got.Articulations); !reflect.DeepEqual(a, test.articulations) {
+			t.Errorf("for %q wanted articulations %v got %v", test.name, test.articulations, a)
+		}
+		var studs []int
+		for _, c := range got.WeakSeams {
+			studs = append(studs, c.Studs)
+		}
+		if !reflect.DeepEqual(studs, test.weakStuds) {
+			t.Errorf("for %q wanted weak seams with %v studs got %v", test.name, test.weakStuds, studs)
+		}
+	}
+}
+
+func TestAnalyzeConnectivityLayers(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		o          ViewOrientation
+		opts       MosaicOptions
+		components int
+	}{
+		{"studs out alone", StudsOut, MosaicOptions{}, 10},
+		{"studs out on a backing", StudsOut, MosaicOptions{Backing: &Backing{Color: Black}}, 1},
+		{"one piece per unbonded baseplate", StudsOut, MosaicOptions{Baseplate: Baseplate16}, 2},
+		{"studs top held by its backing", StudsTop, MosaicOptions{Backing: &Backing{Color: Black}}, 1},
+	} {
+		test.opts.Bricks = []Brick{OneByTwo, OneByOne, OneByTwoPlate, OneByOnePlate}
+		plan, err := CreateGridMosaic(uniformIdeal{test.o, 20, 1, BrightRed}, GreedySolve, test.opts)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if got := len(AnalyzeConnectivity(plan).Components); got != test.components {
+			t.Errorf("for %q wanted %d components got %d", test.name, test.components, got)
+		}
+	}
+}
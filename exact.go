@@ -0,0 +1,361 @@
+package BrickMosaic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// The exact solver finds the cheapest way to tile a grid with the given pieces using dynamic
+// programming over the "broken profile" of each column. The cells of the grid are visited in
+// column major order (top to bottom, then left to right, the same order GreedySolve uses). At any
+// point during the sweep the only thing that matters about the pieces placed so far is which of the
+// upcoming cells they already cover; that set of cells is the profile. Two partial tilings with the
+// same profile can be completed in exactly the same ways, so only the cheapest of them needs to be
+// kept.
+//
+// Each piece is anchored on the first cell of its extent in column major order. When the sweep
+// reaches an uncovered cell that must be filled, the only pieces that can cover it are the ones
+// anchored there, since every other cell of a piece comes later in the sweep.
+//
+// The number of distinct profiles grows exponentially with the height of the grid, so the solver
+// first splits the grid into its connected regions, which are usually small for any single color.
+// If a region still produces more than maxExactStates profiles, the solver stops being exact and
+// continues as a beam search that only keeps the cheapest beamWidth profiles.
+
+const (
+	// maxExactStates is the most profiles the solver tracks before giving up on an exact answer.
+	maxExactStates = 1 << 13
+	// beamWidth is the number of profiles kept per cell once the solver has fallen back to a
+	// beam search.
+	beamWidth = 1 << 8
+)
+
+// ExactMinCostSolve fills the grid with the cheapest possible combination of pieces, as measured
+// by ApproximateCost. Ties in cost are broken in favor of using fewer pieces. If the grid can be
+// completely filled with the given pieces, the returned Solution will completely fill it.
+//
+// Regions of the grid too large to solve exactly are solved with a beam search, falling back to
+// GreedyMinCostSolve if the beam search cannot find a complete tiling.
+func ExactMinCostSolve(g *Grid, pieces []MosaicPiece) (Solution, error) {
+	return ExactMinCostSolveContext(context.Background(), g, pieces)
+}
+
+// ExactMinCostSolveContext is like ExactMinCostSolve, but stops once the context is done, in which
+// case it returns the regions solved so far and the context's error.
+func ExactMinCostSolveContext(ctx context.Context, g *Grid, pieces []MosaicPiece) (Solution, error) {
+	originalGrid := g.Clone()
+	locs := make(map[Location]MosaicPiece)
+	for _, region := range regions(g) {
+		sub := region.grid(g)
+		placed, ok := solveRegion(ctx, &sub, region, pieces)
+		if err := ctx.Err(); err != nil {
+			return Solution{originalGrid, locs}, err
+		}
+		if !ok {
+			// Beam search came up empty; the greedy approach is at least guaranteed to place
+			// something.
+			solution, _ := GreedyMinCostSolve(&sub, pieces)
+			placed = solution.Pieces
+		}
+		for loc, p := range placed {
+			locs[loc] = p
+			for _, pieceLoc := range p.Extent() {
+				absLoc := loc.Add(pieceLoc)
+				g.State[absLoc.Row][absLoc.Col] = Filled
+			}
+		}
+	}
+	if g.Any(ToBeFilled) {
+		return Solution{originalGrid, locs}, fmt.Errorf("Following locations must still be filled: %v", g.Find(ToBeFilled))
+	}
+	return Solution{originalGrid, locs}, nil
+}
+
+// region is a connected set of cells that must be filled, along with its bounding box.
+type region struct {
+	cells                          []Location
+	minRow, minCol, maxRow, maxCol int
+}
+
+// regions splits the ToBeFilled cells of the grid into 4-connected regions. No piece can span
+// two regions, so they can be solved independently. Regions are returned in column major order
+// of their first cell.
+func regions(g *Grid) []region {
+	seen := NewGrid(g.Rows, g.Cols)
+	var result []region
+	for col := 0; col < g.Cols; col++ {
+		for row := 0; row < g.Rows; row++ {
+			if g.Get(row, col) != ToBeFilled || seen.Get(row, col) == Filled {
+				continue
+			}
+			r := region{minRow: row, minCol: col, maxRow: row, maxCol: col}
+			seen.Set(row, col, Filled)
+			queue := []Location{{row, col}}
+			for len(queue) > 0 {
+				loc := queue[0]
+				queue = queue[1:]
+				r.add(loc)
+				for _, delta := range []Location{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+					next := loc.Add(delta)
+					if g.Get(next.Row, next.Col) == ToBeFilled && seen.Get(next.Row, next.Col) != Filled {
+						seen.Set(next.Row, next.Col, Filled)
+						queue = append(queue, next)
+					}
+				}
+			}
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func (r *region) add(loc Location) {
+	r.cells = append(r.cells, loc)
+	if loc.Row < r.minRow {
+		r.minRow = loc.Row
+	}
+	if loc.Row > r.maxRow {
+		r.maxRow = loc.Row
+	}
+	if loc.Col < r.minCol {
+		r.minCol = loc.Col
+	}
+	if loc.Col > r.maxCol {
+		r.maxCol = loc.Col
+	}
+}
+
+// grid returns a grid the same size as g in which only the cells of the region are ToBeFilled.
+func (r region) grid(g *Grid) Grid {
+	sub := NewGrid(g.Rows, g.Cols)
+	for _, loc := range r.cells {
+		sub.Set(loc.Row, loc.Col, ToBeFilled)
+	}
+	return sub
+}
+
+// anchor returns the first location of the extent in column major order.
+func anchor(extent []Location) Location {
+	a := extent[0]
+	for _, loc := range extent[1:] {
+		if loc.Col < a.Col || (loc.Col == a.Col && loc.Row < a.Row) {
+			a = loc
+		}
+	}
+	return a
+}
+
+// anchoredPiece is a piece whose extent is expressed relative to its anchor cell.
+type anchoredPiece struct {
+	piece MosaicPiece
+	// anchor is the location of the anchor cell within the piece's own extent.
+	anchor Location
+	// offsets of every other cell of the piece relative to the anchor.
+	offsets []Location
+}
+
+func anchorPieces(pieces []MosaicPiece) []anchoredPiece {
+	var result []anchoredPiece
+	for _, p := range pieces {
+		extent := p.Extent()
+		if len(extent) == 0 {
+			continue
+		}
+		a := anchor(extent)
+		ap := anchoredPiece{piece: p, anchor: a}
+		for _, loc := range extent {
+			if loc != a {
+				ap.offsets = append(ap.offsets, Location{loc.Row - a.Row, loc.Col - a.Col})
+			}
+		}
+		result = append(result, ap)
+	}
+	return result
+}
+
+// placement is one piece in a partial tiling. Partial tilings share their common prefix.
+type placement struct {
+	origin Location
+	piece  MosaicPiece
+	prev   *placement
+}
+
+// profileState is a partial tiling, along with the set of upcoming cells it already covers.
+type profileState struct {
+	// covered is a ring buffer of bits, indexed by the column major index of a cell within the
+	// region's bounding box, modulo the window size.
+	covered   []byte
+	cost      int
+	numPieces int
+	trail     *placement
+}
+
+func (s profileState) isSet(bit int) bool {
+	return s.covered[bit/8]&(1<<uint(bit%8)) != 0
+}
+
+func (s profileState) better(o profileState) bool {
+	if s.cost != o.cost {
+		return s.cost < o.cost
+	}
+	return s.numPieces < o.numPieces
+}
+
+// profileSet holds the distinct profiles reachable after a given cell, in a deterministic order.
+type profileSet struct {
+	states []profileState
+	index  map[string]int
+}
+
+func newProfileSet() *profileSet {
+	return &profileSet{index: make(map[string]int)}
+}
+
+// add records the state, keeping only the best tiling for each profile.
+func (ps *profileSet) add(s profileState) {
+	key := string(s.covered)
+	if i, ok := ps.index[key]; ok {
+		if s.better(ps.states[i]) {
+			ps.states[i] = s
+		}
+		return
+	}
+	ps.index[key] = len(ps.states)
+	ps.states = append(ps.states, s)
+}
+
+// prune keeps only the n best states. Ties keep their existing order, so the result is
+// deterministic.
+func (ps *profileSet) prune(n int) {
+	if len(ps.states) <= n {
+		return
+	}
+	sort.Stable(byBetter(ps.states))
+	kept := newProfileSet()
+	for _, s := range ps.states[:n] {
+		kept.add(s)
+	}
+	*ps = *kept
+}
+
+// byBetter sorts profile states, best first.
+type byBetter []profileState
+
+func (b byBetter) Len() int {
+	return len(b)
+}
+
+func (b byBetter) Less(i, j int) bool {
+	return b[i].better(b[j])
+}
+
+func (b byBetter) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+// solveRegion runs the profile dynamic program over a single region of g. It returns the pieces
+// of the best tiling found, and false if no complete tiling was found or the context is done.
+func solveRegion(ctx context.Context, g *Grid, r region, pieces []MosaicPiece) (map[Location]MosaicPiece, bool) {
+	anchored := anchorPieces(pieces)
+	height := r.maxRow - r.minRow + 1
+	// The window must be large enough to hold every cell a piece anchored at the current cell
+	// could cover.
+	maxCols := 1
+	for _, ap := range anchored {
+		for _, off := range ap.offsets {
+			if off.Col+1 > maxCols {
+				maxCols = off.Col + 1
+			}
+		}
+	}
+	window := height * maxCols
+	numBytes := (window + 7) / 8
+
+	current := newProfileSet()
+	current.add(profileState{covered: make([]byte, numBytes)})
+	limit := maxExactStates
+
+	for col := r.minCol; col <= r.maxCol; col++ {
+		if ctx.Err() != nil {
+			return nil, false
+		}
+		for row := r.minRow; row <= r.maxRow; row++ {
+			index := (col-r.minCol)*height + (row - r.minRow)
+			bit := index % window
+			next := newProfileSet()
+			for _, s := range current.states {
+				if s.isSet(bit) {
+					covered := make([]byte, numBytes)
+					copy(covered, s.covered)
+					covered[bit/8] &^= 1 << uint(bit%8)
+					next.add(profileState{covered, s.cost, s.numPieces, s.trail})
+					continue
+				}
+				if g.Get(row, col) != ToBeFilled {
+					next.add(s)
+					continue
+				}
+				for _, ap := range anchored {
+					covered, ok := place(g, r, s, ap, row, col, height, window)
+					if !ok {
+						continue
+					}
+					next.add(profileState{
+						covered:   covered,
+						cost:      s.cost + ap.piece.ApproximateCost(),
+						numPieces: s.numPieces + 1,
+						trail: &placement{
+							origin: Location{row - ap.anchor.Row, col - ap.anchor.Col},
+							piece:  ap.piece,
+							prev:   s.trail,
+						},
+					})
+				}
+			}
+			if len(next.states) > limit {
+				// Too many profiles to be exact; from here on out only keep the best.
+				limit = beamWidth
+				next.prune(limit)
+			}
+			current = next
+		}
+	}
+
+	var best *profileState
+	for i, s := range current.states {
+		if best == nil || s.better(*best) {
+			best = &current.states[i]
+		}
+	}
+	if best == nil {
+		return nil, false
+	}
+	result := make(map[Location]MosaicPiece)
+	for p := best.trail; p != nil; p = p.prev {
+		result[p.origin] = p.piece
+	}
+	return result, true
+}
+
+// place attempts to anchor the piece at (row, col). If every cell of the piece is in the region,
+// must be filled and is not yet covered, it returns the profile after placing the piece.
+func place(g *Grid, r region, s profileState, ap anchoredPiece, row, col, height, window int) ([]byte, bool) {
+	base := (col-r.minCol)*height + (row - r.minRow)
+	for _, off := range ap.offsets {
+		absRow, absCol := row+off.Row, col+off.Col
+		if absRow < r.minRow || absRow > r.maxRow || absCol > r.maxCol || g.Get(absRow, absCol) != ToBeFilled {
+			return nil, false
+		}
+		if s.isSet((base + off.Col*height + off.Row) % window) {
+			return nil, false
+		}
+	}
+	covered := make([]byte, len(s.covered))
+	copy(covered, s.covered)
+	for _, off := range ap.offsets {
+		bit := (base + off.Col*height + off.Row) % window
+		covered[bit/8] |= 1 << uint(bit%8)
+	}
+	return covered, true
+}
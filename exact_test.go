@@ -0,0 +1,151 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"testing"
+)
+
+var oneByThree = StudsOutPiece(OneByThree)
+
+// coverCount returns how many times each location of the grid is covered by the solution.
+func coverCount(s Solution) map[Location]int {
+	counts := make(map[Location]int)
+	for loc, p := range s.Pieces {
+		for _, rel := range p.Extent() {
+			counts[loc.Add(rel)]++
+		}
+	}
+	return counts
+}
+
+// checkCovered verifies that every location that had to be filled in the original grid is
+// covered exactly once, and that nothing else is covered.
+func checkCovered(t *testing.T, name string, s Solution) {
+	counts := coverCount(s)
+	for _, loc := range s.Original.Find(ToBeFilled) {
+		if counts[loc] != 1 {
+			t.Errorf("for %q location %v covered %d times", name, loc, counts[loc])
+		}
+		delete(counts, loc)
+	}
+	for loc := range counts {
+		t.Errorf("for %q location %v should not be covered", name, loc)
+	}
+}
+
+func TestExactMinCostSolveGreedyFixtures(t *testing.T) {
+	for _, test := range greedySolveTests {
+		greedyGrid := test.g.Clone()
+		greedy, greedyErr := GreedySolve(&greedyGrid, test.p)
+
+		exactGrid := test.g.Clone()
+		got, err := ExactMinCostSolve(&exactGrid, test.p)
+		if err != nil && !test.hasErr {
+			t.Errorf("for %q wanted no error got %v", test.name, err)
+		} else if err == nil && test.hasErr {
+			t.Errorf("for %q should have had an error", test.name)
+		}
+		if greedyErr != nil || err != nil {
+			continue
+		}
+		checkCovered(t, test.name, got)
+		if got.Cost() > greedy.Cost() {
+			t.Errorf("for %q exact cost %d exceeds greedy cost %d", test.name, got.Cost(), greedy.Cost())
+		}
+	}
+}
+
+func TestExactMinCostSolve(t *testing.T) {
+	for _, test := range []struct {
+		solveTest
+		cost int
+	}{
+		{
+			solveTest{
+				"greedy leaves a hole, exact does not",
+				WithState(1, 5, ToBeFilled),
+				[]MosaicPiece{oneByTwo, oneByThree},
+				map[Location]MosaicPiece{
+					Location{0, 0}: oneByTwo,
+					Location{0, 2}: oneByThree,
+				},
+				false,
+			},
+			9,
+		},
+		{
+			solveTest{
+				"two small pieces are cheaper than one large one",
+				WithState(1, 4, ToBeFilled),
+				[]MosaicPiece{oneByFour, oneByTwo},
+				map[Location]MosaicPiece{
+					Location{0, 0}: oneByTwo,
+					Location{0, 2}: oneByTwo,
+				},
+				false,
+			},
+			4,
+		},
+		{
+			solveTest{
+				"vertical pieces",
+				WithState(4, 2, ToBeFilled),
+				[]MosaicPiece{oneByOne, fourByOne},
+				map[Location]MosaicPiece{
+					Location{0, 0}: fourByOne,
+					Location{0, 1}: fourByOne,
+				},
+				false,
+			},
+			14,
+		},
+		{
+			solveTest{
+				"no tiling exists",
+				WithState(1, 3, ToBeFilled),
+				[]MosaicPiece{oneByTwo},
+				map[Location]MosaicPiece{
+					Location{0, 0}: oneByTwo,
+				},
+				true,
+			},
+			2,
+		},
+	} {
+		got, err := ExactMinCostSolve(&test.g, test.p)
+		if err != nil && !test.hasErr {
+			t.Errorf("for %q wanted no error got %v", test.name, err)
+		} else if err == nil && test.hasErr {
+			t.Errorf("for %q should have had an error", test.name)
+		}
+		if !reflect.DeepEqual(got.Pieces, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got.Pieces)
+		}
+		if got.Cost() != test.cost {
+			t.Errorf("for %q wanted cost %d got %d", test.name, test.cost, got.Cost())
+		}
+	}
+}
+
+func TestExactMinCostSolveSeparateRegions(t *testing.T) {
+	g := WithState(3, 3, ToBeFilled)
+	// Wall off the middle column; the two sides must be solved on their own.
+	for row := 0; row < 3; row++ {
+		g.Set(row, 1, Empty)
+	}
+	got, err := ExactMinCostSolve(&g, []MosaicPiece{oneByOne, twoByTwo})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	checkCovered(t, "separate regions", got)
+}
+
+func TestExactMinCostSolveLargeGrid(t *testing.T) {
+	// Far too large to solve exactly; the beam search must still fill every location.
+	g := WithState(24, 24, ToBeFilled)
+	got, err := ExactMinCostSolve(&g, PiecesForOrientation(StudsOut, allBricks()))
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	checkCovered(t, "large grid", got)
+}
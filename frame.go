@@ -0,0 +1,168 @@
+package BrickMosaic
+
+import (
+	"context"
+	"fmt"
+)
+
+// A frame is a border of a single color built around the mosaic, from the same parts and with the
+// same solver. Its pieces are kept in the FrameLayer of the plan. The cells of the frame are in the
+// same coordinates as the mosaic, so those above and to the left of it have negative rows and columns.
+
+// Mount is how a framed mosaic is hung on the wall.
+type Mount int
+
+const (
+	// NoMount leaves the frame plain.
+	NoMount Mount = iota
+	// MountingHoles puts a Technic brick in each top corner of the frame, so the mosaic can be
+	// screwed or nailed to the wall through the holes.
+	MountingHoles
+	// HangingBar puts a plate with a bar handle in the middle of the top of the frame, so the
+	// mosaic can be hung from a hook.
+	HangingBar
+)
+
+func (m Mount) String() string {
+	switch m {
+	case NoMount:
+		return "none"
+	case MountingHoles:
+		return "holes"
+	case HangingBar:
+		return "bar"
+	}
+	return fmt.Sprintf("Mount(%d)", int(m))
+}
+
+// MountForName returns the mount whose String matches name.
+func MountForName(name string) (Mount, error) {
+	for _, m := range []Mount{NoMount, MountingHoles, HangingBar} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown mount %q; wanted one of none, holes or bar", name)
+}
+
+var (
+	// TechnicOneByTwo represents a 1 x 2 Technic brick with a hole. See http://lego.wikia.com/wiki/Part_3700.
+	TechnicOneByTwo = brick{
+		name:   "1x2 technic brick with hole",
+		id:     "3700",
+		width:  1,
+		length: 2,
+		height: 3,
+		cost:   12,
+	}
+	// HandlePlate represents a 1 x 2 plate with a bar handle on the side. See http://brickowl.com/catalog/lego-plate-1-x-2-with-bar-handle-on-side-closed-ends-48336.
+	HandlePlate = brick{
+		name:   "1x2 plate with bar handle",
+		id:     "48336",
+		width:  1,
+		length: 2,
+		height: 1,
+		cost:   8,
+	}
+)
+
+// Frame describes the border to build around the mosaic.
+type Frame struct {
+	// Width of the border, in studs. Across plates it is as many rows as make up the same distance.
+	Width int
+	// Color of the whole frame.
+	Color BrickColor
+	// Mount, if set, adds the parts to hang the mosaic by. Only StudsTop mosaics can be mounted.
+	Mount Mount
+}
+
+// border returns how many rows and columns thick the frame is in the orientation.
+func (f Frame) border(o ViewOrientation) (rows, cols int) {
+	plates := int((LDU(f.Width)*BrickWidth + PlateHeight - 1) / PlateHeight)
+	switch o {
+	case StudsTop:
+		return plates, f.Width
+	case StudsRight:
+		return f.Width, plates
+	}
+	return f.Width, f.Width
+}
+
+// check returns an error if the frame cannot be built around a mosaic in the orientation.
+func (f Frame) check(o ViewOrientation) error {
+	if f.Width < 1 {
+		return fmt.Errorf("frame must be at least 1 stud wide; was %d", f.Width)
+	}
+	if f.Mount != NoMount && o != StudsTop {
+		return fmt.Errorf("only StudsTop mosaics can be mounted with %v", f.Mount)
+	}
+	return nil
+}
+
+// mounts returns the pieces of the mount, placed in the top of a frame grid with the given number
+// of columns.
+func (f Frame) mounts(cols int) map[Location]MosaicPiece {
+	result := make(map[Location]MosaicPiece)
+	switch f.Mount {
+	case MountingHoles:
+		technic := StudsTopPiece(TechnicOneByTwo)
+		result[Location{0, 0}] = technic
+		result[Location{0, cols - technic.Cols()}] = technic
+	case HangingBar:
+		handle := StudsTopPiece(HandlePlate)
+		result[Location{0, (cols - handle.Cols()) / 2}] = handle
+	}
+	return result
+}
+
+// build fills the frame around the mosaic with the pieces, after putting the mount in place. The
+// pieces that were not in stock are returned along with any error from the solver.
+func (f Frame) build(ctx context.Context, m Ideal, solver GridSolver, pieces []MosaicPiece, stock *Stock) ([]PlacedBrick, []Brick, error) {
+	borderRows, borderCols := f.border(m.Orientation())
+	g := NewGrid(m.NumRows()+2*borderRows, m.NumCols()+2*borderCols)
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			if row < borderRows || row >= borderRows+m.NumRows() || col < borderCols || col >= borderCols+m.NumCols() {
+				g.Set(row, col, ToBeFilled)
+			}
+		}
+	}
+	mounts := f.mounts(g.Cols)
+	for _, origin := range sortedLocations(mounts) {
+		for _, loc := range mounts[origin].Extent() {
+			g.Set(origin.Row+loc.Row, origin.Col+loc.Col, Filled)
+		}
+	}
+
+	var solution Solution
+	var missing []Brick
+	var err error
+	if stock == nil {
+		solution, err = solver(&g, pieces)
+	} else {
+		solution, missing, err = solveWithStock(ctx, &g, solver, pieces, f.Color, stock)
+	}
+	if solution.Pieces == nil {
+		solution.Pieces = make(map[Location]MosaicPiece)
+	}
+	for origin, p := range mounts {
+		solution.Pieces[origin] = p
+	}
+
+	var placed []PlacedBrick
+	offset := Location{-borderRows, -borderCols}
+	for _, loc := range sortedLocations(solution.Pieces) {
+		piece := solution.Pieces[loc]
+		placed = append(placed, PlacedBrick{
+			Id:          len(placed),
+			Origin:      loc.Add(offset),
+			Locs:        piece.Extent(),
+			Color:       f.Color,
+			Shape:       piece,
+			Orientation: m.Orientation(),
+			Rotated:     piece.Rotated(),
+			Layer:       FrameLayer,
+		})
+	}
+	return placed, missing, err
+}
@@ -0,0 +1,119 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFrameBorder(t *testing.T) {
+	for _, test := range []struct {
+		o          ViewOrientation
+		rows, cols int
+	}{
+		{StudsOut, 2, 2},
+		{StudsTop, 5, 2},
+		{StudsRight, 2, 5},
+	} {
+		if rows, cols := (Frame{Width: 2}).border(test.o); rows != test.rows || cols != test.cols {
+			t.Errorf("for %v wanted %d x %d got %d x %d", test.o, test.rows, test.cols, rows, cols)
+		}
+	}
+}
+
+func TestFrame(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		ideal Ideal
+		frame Frame
+		// Cells of the frame, which are not part of the mosaic.
+		cells int
+		mount Brick
+	}{
+		{
+			name:  "studs out",
+			ideal: uniformIdeal{StudsOut, 4, 6, BrightRed},
+			frame: Frame{Width: 1, Color: Black},
+			cells: 6*8 - 4*6,
+		},
+		{
+			name:  "studs top with holes",
+			ideal: uniformIdeal{StudsTop, 6, 6, BrightRed},
+			frame: Frame{Width: 1, Color: Black, Mount: MountingHoles},
+			cells: 12*8 - 6*6,
+			mount: TechnicOneByTwo,
+		},
+		{
+			name:  "studs top with a bar",
+			ideal: uniformIdeal{StudsTop, 6, 6, BrightRed},
+			frame: Frame{Width: 1, Color: BrightRed, Mount: HangingBar},
+			cells: 12*8 - 6*6,
+			mount: HandlePlate,
+		},
+	} {
+		plan, err := CreateGridMosaic(test.ideal, GreedySolve, MosaicOptions{Frame: &test.frame})
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		covered := make(map[Location]bool)
+		mounts := 0
+		for _, p := range plan.(LayeredPlan).Layer(FrameLayer) {
+			if BaseBrick(p.Shape) == test.mount {
+				mounts++
+			}
+			for _, loc := range p.Extent() {
+				cell := p.Origin.Add(loc)
+				inside := cell.Row >= 0 && cell.Row < test.ideal.NumRows() && cell.Col >= 0 && cell.Col < test.ideal.NumCols()
+				if inside || covered[cell] {
+					t.Errorf("for %q frame piece %v covers %v twice or inside the mosaic", test.name, p, cell)
+				}
+				covered[cell] = true
+			}
+		}
+		if len(covered) != test.cells {
+			t.Errorf("for %q wanted %d frame cells got %d", test.name, test.cells, len(covered))
+		}
+		if test.mount != nil && mounts == 0 {
+			t.Errorf("for %q wanted the frame to be mounted with %v", test.name, test.mount.Name())
+		}
+
+		frame := plan.(LayeredPlan).LayerInventory(FrameLayer)
+		all := plan.Inventory()
+		if got, want := len(all.PiecesForColor(test.frame.Color)), len(plan.(LayeredPlan).Layer(FrameLayer)); got < want {
+			t.Errorf("for %q wanted the frame's %d pieces in the inventory got %d", test.name, want, got)
+		}
+		if got := len(frame.UsageForColorMap()); got != 1 {
+			t.Errorf("for %q wanted a single color in the frame's inventory got %d", test.name, got)
+		}
+	}
+}
+
+func TestFrameErrors(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		ideal Ideal
+		frame Frame
+	}{
+		{"no width", uniformIdeal{StudsOut, 4, 4, BrightRed}, Frame{Color: Black}},
+		{"mount studs out", uniformIdeal{StudsOut, 4, 4, BrightRed}, Frame{Width: 1, Color: Black, Mount: HangingBar}},
+	} {
+		if _, err := CreateGridMosaic(test.ideal, GreedySolve, MosaicOptions{Frame: &test.frame}); err == nil {
+			t.Errorf("for %q wanted an error", test.name)
+		}
+	}
+}
+
+func TestSVGRenderFrame(t *testing.T) {
+	ideal := uniformIdeal{StudsOut, 4, 4, BrightRed}
+	plan, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{Frame: &Frame{Width: 1, Color: Black}})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	got := SVGRenderer{}.Render(plan)
+	width, _ := GetDimensionsForBlock(StudsOut)
+	for _, want := range []string{`id="frame"`, fmt.Sprintf(`width="%d"`, 6*width)} {
+		if !strings.Contains(got, want) {
+			t.Errorf("wanted %q in the svg", want)
+		}
+	}
+}
@@ -24,12 +24,16 @@ func GreedySolve(g *Grid, pieces []MosaicPiece) (Solution, error) {
 			loc := Location{row, col}
 			if g.Get(row, col) == ToBeFilled {
 				for _, p := range pieces {
+					// The piece's upper left cell goes here. That is usually its origin, unless the
+					// piece is not a rectangle and has no cell in that corner.
+					a := AnchorCell(p.Extent(), UpperLeft)
+					origin := Location{loc.Row - a.Row, loc.Col - a.Col}
 					// We found the best fit! Need to add it to the map, as
 					// well as mark the internal state
-					if g.PieceFits(p.Extent(), loc) {
-						locs[loc] = p
+					if g.PieceFits(p.Extent(), origin) {
+						locs[origin] = p
 						for _, pieceLoc := range p.Extent() {
-							absLoc := loc.Add(pieceLoc)
+							absLoc := origin.Add(pieceLoc)
 							g.State[absLoc.Row][absLoc.Col] = Filled
 						}
 					}
@@ -104,17 +108,16 @@ func SymmetricalGreedySolve(g *Grid, pieces []MosaicPiece) (Solution, error) {
 			fmt.Printf("Loc %v AnchorPoint %v\n", loc, anchorPoint)
 			if g.Get(row, col) == ToBeFilled {
 				for _, p := range pieces {
-					// Based on anchor point, translate the extent accordingly
-					translated := Translate(p.Extent(), anchorPoint)
+					// Translate the absolute location here as to where the absolute location of the
+					// upper left corner of the piece is located. Pieces that are not rectangles are
+					// not symmetrical, so check the cells they really cover from there.
+					a := AnchorCell(p.Extent(), anchorPoint)
+					upperLeft := Location{loc.Row - a.Row, loc.Col - a.Col}
 
 					// We found the best fit! Need to add it to the map, as
 					// well as mark the internal state
-					if g.PieceFits(translated, loc) {
+					if g.PieceFits(p.Extent(), upperLeft) {
 						fmt.Printf("Piece %v fits \n", p.Name())
-
-						// Translate the absolute location here as to where the absolute location of the
-						// upper left corner of the piece is located.
-						upperLeft := TranslateAbsoluteOrigin(loc, p, anchorPoint)
 						fmt.Printf("Upper left %v\n", upperLeft)
 						locs[upperLeft] = p
 						for _, pieceLoc := range p.Extent() {
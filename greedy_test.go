@@ -53,47 +53,52 @@ func TestGreedySymmetricalSolve(t *testing.T) {
 	}
 }
 
-func TestGreedySolve(t *testing.T) {
-	for _, test := range []solveTest{
-		{
-			"cannot be solved - no pieces",
-			WithState(1, 1, ToBeFilled),
-			[]MosaicPiece{},
-			make(map[Location]MosaicPiece),
-			true,
-		},
-		{
-			"trivially solved - one piece",
-			WithState(1, 1, ToBeFilled),
-			[]MosaicPiece{oneByOne},
-			map[Location]MosaicPiece{
-				Location{0, 0}: oneByOne,
-			},
-			false,
+// greedySolveTests are shared with the other solvers; any grid that GreedySolve can fill, they
+// must be able to fill as well.
+var greedySolveTests = []solveTest{
+	{
+		"cannot be solved - no pieces",
+		WithState(1, 1, ToBeFilled),
+		[]MosaicPiece{},
+		make(map[Location]MosaicPiece),
+		true,
+	},
+	{
+		"trivially solved - one piece",
+		WithState(1, 1, ToBeFilled),
+		[]MosaicPiece{oneByOne},
+		map[Location]MosaicPiece{
+			Location{0, 0}: oneByOne,
 		},
-		{
-			"trivially solved - one piece, 2x2",
-			WithState(2, 2, ToBeFilled),
-			[]MosaicPiece{twoByFour, twoByTwo, oneByOne},
-			map[Location]MosaicPiece{
-				Location{0, 0}: twoByTwo,
-			},
-			false,
+		false,
+	},
+	{
+		"trivially solved - one piece, 2x2",
+		WithState(2, 2, ToBeFilled),
+		[]MosaicPiece{twoByFour, twoByTwo, oneByOne},
+		map[Location]MosaicPiece{
+			Location{0, 0}: twoByTwo,
 		},
-		{
-			"5 x 5 grid - 2 2x4",
-			WithState(5, 5, ToBeFilled),
-			[]MosaicPiece{twoByFour, twoByTwo, oneByFour, fourByOne, oneByOne},
-			map[Location]MosaicPiece{
-				Location{0, 0}: twoByFour,
-				Location{2, 0}: twoByFour,
-				Location{0, 4}: fourByOne,
-				Location{4, 0}: oneByFour,
-				Location{4, 4}: oneByOne,
-			},
-			false,
+		false,
+	},
+	{
+		"5 x 5 grid - 2 2x4",
+		WithState(5, 5, ToBeFilled),
+		[]MosaicPiece{twoByFour, twoByTwo, oneByFour, fourByOne, oneByOne},
+		map[Location]MosaicPiece{
+			Location{0, 0}: twoByFour,
+			Location{2, 0}: twoByFour,
+			Location{0, 4}: fourByOne,
+			Location{4, 0}: oneByFour,
+			Location{4, 4}: oneByOne,
 		},
-	} {
+		false,
+	},
+}
+
+func TestGreedySolve(t *testing.T) {
+	for _, test := range greedySolveTests {
+		test.g = test.g.Clone()
 		got, err := GreedySolve(&test.g, test.p)
 		if err != nil && !test.hasErr {
 			t.Errorf("for %q wanted no error got %v", test.name, err)
@@ -16,6 +16,7 @@
 package BrickMosaic
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
@@ -23,6 +24,18 @@ import (
 // GridSolver is the interface for fitting pieces into the given grid.
 type GridSolver func(g *Grid, pieces []MosaicPiece) (Solution, error)
 
+// ContextSolver is a GridSolver that gives up once the context is done, returning what it has
+// placed so far along with the context's error.
+type ContextSolver func(ctx context.Context, g *Grid, pieces []MosaicPiece) (Solution, error)
+
+// Bind returns a GridSolver that solves with the given context, e.g. the one passed to
+// CreateGridMosaicContext, so that cancelling it stops the solver part way through a grid.
+func (s ContextSolver) Bind(ctx context.Context) GridSolver {
+	return func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+		return s(ctx, g, pieces)
+	}
+}
+
 // Location represents one cell in the grid.
 type Location struct {
 	Row, Col int
@@ -58,6 +71,15 @@ type Solution struct {
 	Pieces   map[Location]MosaicPiece
 }
 
+// Cost returns the approximate cost, in cents, of all of the pieces in the solution.
+func (solution Solution) Cost() int {
+	cost := 0
+	for _, p := range solution.Pieces {
+		cost += p.ApproximateCost()
+	}
+	return cost
+}
+
 // NewGrid returns an empty grid of size numRows by numCols.
 func NewGrid(numRows, numCols int) Grid {
 	grid := make([][]State, numRows)
@@ -9,6 +9,8 @@ import (
 
 type Inventory struct {
 	pieces map[BrickColor][]Brick
+	// prices, if set, overrides the price of the pieces in particular colors.
+	prices *PriceTable
 }
 
 type Usage struct {
@@ -56,20 +58,28 @@ func (inventory Inventory) DescendingUsage() []ColorUsage {
 }
 
 func MakeInventory() Inventory {
-	return Inventory{make(map[BrickColor][]Brick)}
+	return Inventory{pieces: make(map[BrickColor][]Brick)}
 }
 
+// SetPrices makes ApproximateCost use the prices in the table, for the parts and colors it has.
+func (inventory *Inventory) SetPrices(t *PriceTable) {
+	inventory.prices = t
+}
+
+// Add adds the part to the inventory. Pieces that are the same part, however they are oriented in
+// the mosaic, are counted as that part.
 func (inventory *Inventory) Add(c BrickColor, p Brick) {
-	inventory.pieces[c] = append(inventory.pieces[c], p)
+	inventory.pieces[c] = append(inventory.pieces[c], BaseBrick(p))
 }
 
 // ApproximateCost estimates how much the mosaic will cost to build, given
-// the price information embedded in the pieces. Returns a value in cents.
+// the price information embedded in the pieces, or the price table if one is set.
+// Returns a value in cents.
 func (inventory *Inventory) ApproximateCost() int {
 	cost := 0
-	for _, pieces := range inventory.pieces {
+	for color, pieces := range inventory.pieces {
 		for _, p := range pieces {
-			cost += p.ApproximateCost()
+			cost += inventory.prices.Price(p, color)
 		}
 	}
 	return cost
@@ -12,8 +12,11 @@ import (
 	_ "image/png"
 	//	"path/filepath"
 	//	"image/gif"
+	"io/ioutil"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/I82Much/BrickMosaic"
 )
@@ -25,9 +28,41 @@ var (
 	orientation  = flag.String("orientation", "STUDS_RIGHT", "how the grid should be oriented. Either STUDS_RIGHT, STUDS_OUT, or STUDS_TOP")
 	inputPath    = flag.String("path", "", "path to input file")
 	outputPath   = flag.String("output_path", "", "path to output svg file")
-	palette      = flag.String("palette", "full", "comma separated list of color names, or predefined color palette name")
+	palette      = flag.String("palette", "full", "comma separated list of color names, predefined color palette name, or file:path to an LDConfig.ldr, BrickLink or Rebrickable color file")
 	dither       = flag.Bool("dither", true, "If true, use dithering when converting the imagine into a mosaic")
-	solver       = flag.String("solver", "greedy", "The solver to use; either 'greedy' or 'cost'")
+	space        = flag.String("average_space", "srgb", "color space the pixels of each stud are combined in; one of 'srgb', 'linear' or 'lab'")
+	reducer      = flag.String("reducer", "mean", "how the pixels of each stud are combined into one color; one of 'mean', 'median' or 'mode'")
+	chooseColors = flag.Int("choose_colors", 0, "if set, choose this many colors out of --palette that best match the image, and print them")
+	include      = flag.String("include_colors", "", "comma separated list of color names that --choose_colors must include")
+	quota        = flag.String("quota", "", "comma separated color:count pairs limiting the number of studs of each color, e.g. 'Black:800,White:300'. Turns off dithering")
+	maskAlpha    = flag.Float64("mask_alpha", 0, "leave out the studs that are mostly pixels less opaque than this, from 0 to 1. If 0, opacity is ignored")
+	chromaKey    = flag.String("chroma_key", "", "if set, a hex color such as #00FF00; leave out the studs that are mostly pixels close to it")
+	keyTolerance = flag.Float64("chroma_key_tolerance", 30, "how far, by --color_distance, a pixel may be from --chroma_key and still be left out")
+	maskPath     = flag.String("mask", "", "path to a mask image, stretched over the input; leave out the studs that are mostly dark or transparent in it")
+	distance     = flag.String("color_distance", "rgb", "how to measure the difference between colors when matching them to bricks; one of 'rgb', 'cie76', 'cie94' or 'ciede2000'")
+	solver       = flag.String("solver", "greedy", "The solver to use; one of 'greedy', 'symmetrical', 'cost', 'symmetricalcost', 'exact', 'anytime' or 'runningbond'")
+	solveTimeout = flag.Duration("solve_timeout", 10*time.Second, "how long the 'anytime' solver may spend on each color")
+	optimize     = flag.String("optimize", "", "If set, improve the solution with local search; one of 'cost', 'pieces' or 'seams'")
+	iterations   = flag.Int("optimize_iterations", 0, "number of local search moves to attempt per color. If 0, ten per piece")
+	temperature  = flag.Float64("optimize_temperature", 1.0, "starting temperature for simulated annealing; 0 for hill climbing")
+	seed         = flag.Int64("seed", 1, "seed for the random number generator")
+	islands      = flag.Bool("highlight_islands", false, "If true, outline the groups of bricks that are not connected to the rest of the mosaic")
+	seamWeight   = flag.Int("seam_weight", BrickMosaic.DefaultSeamWeight, "penalty in cents the 'runningbond' solver applies to each seam lined up with the seam beneath it")
+	stockPath    = flag.String("stock", "", "path to a CSV file of color,part id,count limiting the parts that may be used")
+	workers      = flag.Int("workers", 0, "number of colors to solve in parallel. If 0, one per CPU")
+	catalogPath  = flag.String("catalog", "", "path to a JSON or CSV catalog of the parts to build with. If unset, the standard bricks and plates")
+	pricesPath   = flag.String("prices", "", "path to a BrickLink style price guide CSV giving the price of parts in each color")
+	parts        = flag.String("parts", "", "comma separated part ids, 'bricks' or 'plates' to build with; prefix with - to leave out, e.g. 'plates' or '-3005'")
+	colorParts   = flag.String("parts_for_color", "", "semicolon separated color:parts pairs restricting the parts in that color, e.g. 'Transparent:3024,3023'")
+	available    = flag.String("availability", "", "path to a CSV file of part id followed by every color that part was made in. If unset, the built-in dataset; 'none' to use every part in every color")
+	baseplate    = flag.Int("baseplate", 0, "for STUDS_OUT mosaics, size of the baseplates to build on; one of 16, 32 or 48. If 0, no baseplates")
+	bond         = flag.Bool("bond_baseplates", false, "If true, let bricks straddle the seams between baseplates")
+	frameWidth   = flag.Int("frame", 0, "width in studs of a frame to build around the mosaic. If 0, no frame")
+	frameColor   = flag.String("frame_color", "Black", "color of the frame")
+	backingColor = flag.String("backing", "", "if set, build a hidden backing layer in this color behind the mosaic to hold it together")
+	backingPath  = flag.String("backing_output_path", "", "path to an output svg file showing the backing layer")
+	mount        = flag.String("mount", "none", "for STUDS_TOP mosaics, how to hang the framed mosaic; one of 'none', 'holes' or 'bar'")
+	order        = flag.String("order", "", "order the solver tries the pieces in; comma separated list of 'area', 'cost', 'costpercell' or 'length', later ones breaking ties")
 
 	orientationMap = map[string]BrickMosaic.ViewOrientation{
 		"STUDS_RIGHT": BrickMosaic.StudsRight,
@@ -49,6 +84,7 @@ var (
 		"symmetrical":     BrickMosaic.SymmetricalGreedySolve,
 		"cost":            BrickMosaic.GreedyMinCostSolve,
 		"symmetricalcost": BrickMosaic.SymmetricalGreedyMinCostSolve,
+		"exact":           BrickMosaic.ExactMinCostSolve,
 	}
 )
 
@@ -56,6 +92,13 @@ func paletteFromArg(p string) color.Palette {
 	if palette, ok := paletteMap[p]; ok {
 		return palette
 	}
+	if strings.HasPrefix(p, "file:") {
+		colors, err := BrickMosaic.LoadPalette(strings.TrimPrefix(p, "file:"))
+		if err != nil {
+			panic(err)
+		}
+		return BrickMosaic.ColorPalette(colors)
+	}
 	// Treat this as comma separated list
 	colorStrings := strings.Split(p, ",")
 	var colors []color.Color
@@ -127,30 +170,275 @@ func main() {
 		panic("must set (--rows and --cols) or --studs")
 	}
 
+	opts := BrickMosaic.MosaicOptions{Workers: *workers, Bricks: BrickMosaic.Pieces}
+	if *catalogPath != "" {
+		opts.Bricks, err = BrickMosaic.LoadCatalog(*catalogPath)
+		if err != nil {
+			panic(fmt.Sprintf("Couldn't load catalog %q: %v", *catalogPath, err))
+		}
+	}
+	if *pricesPath != "" {
+		pricesFile, err := os.Open(*pricesPath)
+		if err != nil {
+			panic(err)
+		}
+		opts.Prices, err = BrickMosaic.ParsePriceGuide(pricesFile)
+		pricesFile.Close()
+		if err != nil {
+			panic(err)
+		}
+		if skipped := opts.Prices.Skipped(); len(skipped) > 0 {
+			fmt.Printf("Unknown colors in %v, priced at their approximate cost: %v\n", *pricesPath, strings.Join(skipped, ", "))
+		}
+		for _, clash := range opts.Prices.Clashes() {
+			fmt.Printf("Colors named twice in %v: %v\n", *pricesPath, clash)
+		}
+	}
+	if *stockPath != "" {
+		stockFile, err := os.Open(*stockPath)
+		if err != nil {
+			panic(err)
+		}
+		opts.Stock, err = BrickMosaic.ParseStock(stockFile, opts.Bricks)
+		stockFile.Close()
+		if err != nil {
+			panic(err)
+		}
+	}
+	if *parts != "" {
+		opts.Bricks, err = BrickMosaic.SelectParts(opts.Bricks, *parts)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if *colorParts != "" {
+		opts.PartsForColor, err = BrickMosaic.ParsePartsForColor(opts.Bricks, *colorParts)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if *order != "" {
+		opts.Order, err = BrickMosaic.OrderingForName(*order)
+		if err != nil {
+			panic(err)
+		}
+	}
+	// Leave the colors no part is made in out of the palette, rather than fail once they are chosen.
+	if *available != "none" {
+		opts.Availability = BrickMosaic.DefaultAvailability()
+		if *available != "" {
+			availableFile, err := os.Open(*available)
+			if err != nil {
+				panic(err)
+			}
+			opts.Availability, err = BrickMosaic.ParseAvailability(availableFile)
+			availableFile.Close()
+			if err != nil {
+				panic(err)
+			}
+		}
+		available := opts.Availability.Palette(palette, opts.Bricks, viewOrientation)
+		var left []BrickMosaic.BrickColor
+		for i, j := 0, 0; i < len(palette); i++ {
+			if j < len(available) && available[j] == palette[i] {
+				j++
+			} else if c, ok := palette[i].(BrickMosaic.BrickColor); ok {
+				left = append(left, c)
+			}
+		}
+		if len(left) > 0 {
+			fmt.Printf("Leaving out colors no 1x1 part is made in: %v\n", BrickMosaic.PaletteString(left))
+		}
+		palette = available
+		if len(palette) == 0 {
+			panic("no color in the palette is available")
+		}
+	}
+
 	// What is the ideal representation of the mosaic? Handles downsampling from many colors to few.
-	var ideal BrickMosaic.Ideal
+	colorDistance, err := BrickMosaic.ColorDistanceForName(*distance)
+	if err != nil {
+		panic(err)
+	}
+	posterizeOpts := BrickMosaic.PosterizeOptions{Distance: colorDistance}
+	posterizeOpts.Space, err = BrickMosaic.ColorSpaceForName(*space)
+	if err != nil {
+		panic(err)
+	}
+	posterizeOpts.Reducer, err = BrickMosaic.ReducerForName(*reducer)
+	if err != nil {
+		panic(err)
+	}
 	if *dither {
-		ideal = BrickMosaic.DitherPosterize(img, palette, numRows, numCols, viewOrientation)
+		posterizeOpts.Dither = 1.0
+	}
+	posterizeOpts.Mask = BrickMosaic.Mask{Alpha: *maskAlpha, KeyTolerance: *keyTolerance}
+	if *chromaKey != "" {
+		posterizeOpts.Mask.Key, err = BrickMosaic.ParseHexColor(*chromaKey)
+		if err != nil {
+			panic(err)
+		}
+	}
+	if *maskPath != "" {
+		maskFile, err := os.Open(*maskPath)
+		if err != nil {
+			panic(err)
+		}
+		posterizeOpts.Mask.Image, _, err = image.Decode(maskFile)
+		maskFile.Close()
+		if err != nil {
+			panic(fmt.Sprintf("Couldn't decode mask %v: %v", *maskPath, err))
+		}
+	}
+	if *chooseColors > 0 {
+		var mustInclude []BrickMosaic.BrickColor
+		if *include != "" {
+			for _, name := range strings.Split(*include, ",") {
+				c := BrickMosaic.ColorForName(name)
+				if c == nil {
+					panic(fmt.Sprintf("unknown color %q", name))
+				}
+				mustInclude = append(mustInclude, *c)
+			}
+		}
+		chosen, err := BrickMosaic.ChoosePalette(img, numRows, numCols, *chooseColors, BrickMosaic.PaletteOptions{
+			Candidates: palette,
+			Include:    mustInclude,
+			Posterize:  posterizeOpts,
+		})
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Chosen palette: --palette=%v\n", BrickMosaic.PaletteString(chosen))
+		palette = BrickMosaic.ColorPalette(chosen)
+	}
+	var ideal BrickMosaic.Ideal
+	if *quota != "" {
+		quotas, err := BrickMosaic.ParseQuota(*quota)
+		if err != nil {
+			panic(err)
+		}
+		quotaImage, err := BrickMosaic.QuotaPosterize(img, palette, numRows, numCols, viewOrientation, quotas, posterizeOpts)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("Colors are %.1f apart on average (%v)\n", quotaImage.MeanColorDistance(), colorDistance)
+		if binding := quotaImage.Binding(); len(binding) > 0 {
+			fmt.Printf("Quotas bind for: %v\n", BrickMosaic.PaletteString(binding))
+		}
+		ideal = quotaImage
 	} else {
-		ideal = BrickMosaic.EucPosterize(img, palette, numRows, numCols, viewOrientation)
+		brickImage := BrickMosaic.PosterizeWith(img, palette, numRows, numCols, viewOrientation, posterizeOpts)
+		fmt.Printf("Colors are %.1f apart on average (%v)\n", brickImage.MeanColorDistance(), colorDistance)
+		ideal = brickImage
 	}
 
 	var gridSolver BrickMosaic.GridSolver
-	if s, ok := solverMap[*solver]; ok {
+	// The anytime solver reports how close each color came to its lower bound.
+	var bounds *BrickMosaic.BoundReport
+	if *solver == "anytime" {
+		bounds = &BrickMosaic.BoundReport{Optimal: true}
+		var mu sync.Mutex
+		gridSolver = BrickMosaic.AnytimeSolver(*solveTimeout, func(r BrickMosaic.BoundReport) {
+			mu.Lock()
+			defer mu.Unlock()
+			*bounds = bounds.Add(r)
+		})
+	} else if *solver == "runningbond" {
+		gridSolver = BrickMosaic.RunningBondSolver(viewOrientation, *seamWeight)
+	} else if s, ok := solverMap[*solver]; ok {
 		gridSolver = s
 	} else {
 		panic(fmt.Sprintf("unknown solver %v; wanted one of %v", *solver, solverMap))
 	}
+	if *baseplate != 0 {
+		opts.Baseplate, err = BrickMosaic.BaseplateForSize(*baseplate)
+		if err != nil {
+			panic(err)
+		}
+		opts.BondBaseplates = *bond
+	}
+	if *frameWidth != 0 {
+		c := BrickMosaic.ColorForName(*frameColor)
+		if c == nil {
+			panic(fmt.Sprintf("unknown frame color %q", *frameColor))
+		}
+		m, err := BrickMosaic.MountForName(*mount)
+		if err != nil {
+			panic(err)
+		}
+		opts.Frame = &BrickMosaic.Frame{Width: *frameWidth, Color: *c, Mount: m}
+	}
+	if *backingColor != "" {
+		c := BrickMosaic.ColorForName(*backingColor)
+		if c == nil {
+			panic(fmt.Sprintf("unknown backing color %q", *backingColor))
+		}
+		opts.Backing = &BrickMosaic.Backing{Color: *c}
+	}
 	// How are we going to build this mosaic?
-	plan := BrickMosaic.CreateGridMosaic(ideal, gridSolver)
+	plan, err := BrickMosaic.CreateGridMosaic(ideal, gridSolver, opts)
+	if plan == nil {
+		panic(err)
+	}
+	if err != nil {
+		// Still render what we have, so the holes can be seen.
+		fmt.Fprintf(os.Stderr, "Could not fill the mosaic: %v\n", err)
+		for _, v := range BrickMosaic.ValidatePlan(plan) {
+			fmt.Fprintf(os.Stderr, "  %v\n", v)
+		}
+	}
+	if bounds != nil {
+		fmt.Printf("Anytime solver: %v\n", *bounds)
+	}
+	if *optimize != "" {
+		objective, err := BrickMosaic.ObjectiveForName(*optimize)
+		if err != nil {
+			panic(err)
+		}
+		plan, err = BrickMosaic.OptimizePlan(plan, BrickMosaic.PiecesForOrientation(viewOrientation, opts.Bricks), BrickMosaic.OptimizeOptions{
+			Objective:   objective,
+			Orientation: viewOrientation,
+			Iterations:  *iterations,
+			Temperature: *temperature,
+			Seed:        *seed,
+			Stock:       opts.Stock,
+		})
+		if err != nil {
+			panic(err)
+		}
+	}
 	inventory := plan.Inventory()
 	fmt.Printf("%v", inventory.DescendingUsage())
-	fmt.Printf("Will cost approximately %d dollars to build", inventory.ApproximateCost()/100)
+	fmt.Printf("Will cost approximately %d dollars to build\n", inventory.ApproximateCost()/100)
+	if layered, ok := plan.(BrickMosaic.LayeredPlan); ok && opts.Frame != nil {
+		frame := layered.LayerInventory(BrickMosaic.FrameLayer)
+		fmt.Printf("Of which the frame is %v", frame.DescendingUsage())
+		fmt.Printf(", costing approximately %d dollars\n", frame.ApproximateCost()/100)
+	}
+	if layered, ok := plan.(BrickMosaic.LayeredPlan); ok && opts.Backing != nil {
+		backing := layered.LayerInventory(BrickMosaic.BackingLayer)
+		fmt.Printf("Of which the backing is %v", backing.DescendingUsage())
+		fmt.Printf(", costing approximately %d dollars\n", backing.ApproximateCost()/100)
+	}
+	if shortfall := plan.Shortfall().DescendingUsage(); len(shortfall) > 0 {
+		fmt.Printf("Not enough parts in stock; still need:\n%v", shortfall)
+	}
+
+	connectivity := BrickMosaic.AnalyzeConnectivity(plan)
+	fmt.Printf("Mosaic holds together in %d piece(s); %d articulation bricks, %d weak seams\n",
+		len(connectivity.Components), len(connectivity.Articulations), len(connectivity.WeakSeams))
 
-	renderer := BrickMosaic.SVGRenderer{}
+	renderer := BrickMosaic.SVGRenderer{HighlightIslands: *islands}
 	if _, err := outputFile.Write([]byte(renderer.Render(plan))); err != nil {
 		panic(err)
 	}
+	if layered, ok := plan.(BrickMosaic.LayeredPlan); ok && *backingPath != "" {
+		backing := BrickMosaic.LayerPlan(layered, BrickMosaic.BackingLayer)
+		if err := ioutil.WriteFile(*backingPath, []byte(BrickMosaic.SVGRenderer{}.Render(backing)), 0644); err != nil {
+			panic(err)
+		}
+	}
 
 	/*
 			// TODO handle this more gracefully
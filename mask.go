@@ -0,0 +1,84 @@
+package BrickMosaic
+
+import (
+	"image"
+	"image/color"
+)
+
+// Not every mosaic is a rectangle: a logo is cut out along its outline, a portrait may be a circle
+// or a silhouette. A Mask picks out the background of the image, and the cells that are mostly
+// background are left out of the mosaic. The background can be the transparent pixels of the
+// image, the pixels close to a chroma key color, or the dark pixels of a separate mask image.
+
+// Mask configures which cells of an image are left out of the mosaic. The zero Mask leaves every
+// cell in.
+type Mask struct {
+	// Alpha is the opacity, from 0 to 1, below which a pixel is background. If 0, opacity is
+	// ignored.
+	Alpha float64
+	// Key is a chroma key color; pixels within KeyTolerance of it are background. If nil, there is
+	// no chroma key.
+	Key color.Color
+	// KeyTolerance is how far from Key a pixel may be, as measured by the ColorDistance the image
+	// is posterized with.
+	KeyTolerance float64
+	// Image is a mask image, stretched over the image; its dark and transparent pixels are
+	// background. If nil, there is no mask image.
+	Image image.Image
+}
+
+// isZero determines whether the mask leaves every cell in.
+func (m Mask) isZero() bool {
+	return m.Alpha == 0 && m.Key == nil && m.Image == nil
+}
+
+// covers determines whether the cell of the image within the rectangle from (x0, y0) to (x1, y1)
+// is masked: whether more than half of it is background.
+func (m Mask) covers(img image.Image, x0, y0, x1, y1 float64, d ColorDistance) bool {
+	if m.isZero() {
+		return false
+	}
+	var background, total float64
+	if m.Alpha != 0 || m.Key != nil {
+		eachPixel(img, x0, y0, x1, y1, func(c color.Color, weight float64) {
+			_, _, _, a := c.RGBA()
+			if m.Alpha != 0 && float64(a)/0xffff < m.Alpha {
+				background += weight
+			} else if m.Key != nil && d.Distance(c, m.Key) <= m.KeyTolerance {
+				background += weight
+			}
+			total += weight
+		})
+	}
+	if m.Image != nil {
+		// The mask image need not be the same size as the image.
+		b, mb := img.Bounds(), m.Image.Bounds()
+		toX := func(x float64) float64 {
+			return doMap(x, float64(b.Min.X), float64(b.Max.X), float64(mb.Min.X), float64(mb.Max.X))
+		}
+		toY := func(y float64) float64 {
+			return doMap(y, float64(b.Min.Y), float64(b.Max.Y), float64(mb.Min.Y), float64(mb.Max.Y))
+		}
+		var maskBackground, maskTotal float64
+		eachPixel(m.Image, toX(x0), toY(y0), toX(x1), toY(y1), func(c color.Color, weight float64) {
+			if color.GrayModel.Convert(c).(color.Gray).Y < 128 {
+				maskBackground += weight
+			}
+			maskTotal += weight
+		})
+		if maskTotal > 0 && maskBackground/maskTotal > 0.5 {
+			return true
+		}
+	}
+	return total > 0 && background/total > 0.5
+}
+
+// ParseHexColor converts a hex color such as "#00FF00" or "00FF00" to an opaque color, e.g. for
+// a chroma key.
+func ParseHexColor(s string) (color.Color, error) {
+	c, err := parseRGB(s)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
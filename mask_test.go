@@ -0,0 +1,110 @@
+package BrickMosaic
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// disc returns a square image of the given size with a disc of one color on a background.
+func disc(size int, fill, background color.Color) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	r := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-r, float64(y)+0.5-r
+			if dx*dx+dy*dy < r*r {
+				img.Set(x, y, fill)
+			} else {
+				img.Set(x, y, background)
+			}
+		}
+	}
+	return img
+}
+
+func TestMask(t *testing.T) {
+	green := color.RGBA{0, 255, 0, 255}
+	for _, test := range []struct {
+		name string
+		img  image.Image
+		mask Mask
+	}{
+		{"alpha", disc(40, BrightRed, color.Transparent), Mask{Alpha: 0.5}},
+		{"chroma key", disc(40, BrightRed, green), Mask{Key: green, KeyTolerance: 30}},
+		{"mask image", disc(40, BrightRed, BrightRed), Mask{Image: disc(20, color.White, color.Black)}},
+	} {
+		opts := PosterizeOptions{Mask: test.mask}
+		si := PosterizeWith(test.img, color.Palette{BrightRed, White}, 4, 4, StudsOut, opts)
+		for row := 0; row < 4; row++ {
+			for col := 0; col < 4; col++ {
+				corner := (row == 0 || row == 3) && (col == 0 || col == 3)
+				if got := si.Masked(row, col); got != corner {
+					t.Errorf("for %q at %v wanted masked %v got %v", test.name, Location{row, col}, corner, got)
+				}
+				if !corner && si.Color(row, col) != BrightRed {
+					t.Errorf("for %q at %v wanted BrightRed got %v", test.name, Location{row, col}, si.Color(row, col))
+				}
+			}
+		}
+
+		plan, err := CreateGridMosaic(si, GreedySolve, MosaicOptions{})
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if got := ValidatePlan(plan); len(got) != 0 {
+			t.Errorf("for %q wanted a valid plan got %v", test.name, got)
+		}
+		cells := 0
+		for _, b := range plan.Pieces() {
+			cells += len(b.Extent())
+		}
+		if cells != 12 {
+			t.Errorf("for %q wanted 12 cells covered got %d", test.name, cells)
+		}
+	}
+}
+
+func TestValidateMasked(t *testing.T) {
+	si := PosterizeWith(disc(40, BrightRed, color.Transparent), color.Palette{BrightRed}, 4, 4, StudsOut, PosterizeOptions{Mask: Mask{Alpha: 0.5}})
+	plan := brickPlan{si, []PlacedBrick{
+		placeBrick(1, StudsOut, OneByFourPlate, Location{0, 0}),
+		placeBrick(2, StudsOut, OneByFourPlate, Location{1, 0}),
+		placeBrick(3, StudsOut, OneByFourPlate, Location{2, 0}),
+		placeBrick(4, StudsOut, OneByFourPlate, Location{3, 0}),
+	}}
+	if got := len(ValidatePlan(plan)); got != 4 {
+		t.Errorf("wanted the 4 masked corners out of bounds got %d violations", got)
+	}
+}
+
+func TestQuotaPosterizeMasked(t *testing.T) {
+	opts := PosterizeOptions{Mask: Mask{Alpha: 0.5}}
+	qi, err := QuotaPosterize(disc(40, BrightRed, color.Transparent), color.Palette{BrightRed}, 4, 4, StudsOut, map[BrickColor]int{BrightRed: 12}, opts)
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	if !qi.Masked(0, 0) || qi.Masked(1, 1) {
+		t.Errorf("wanted only the corners masked")
+	}
+	if got := qi.Used(BrightRed); got != 12 {
+		t.Errorf("wanted 12 BrightRed cells got %d", got)
+	}
+}
+
+func TestTerminalRenderMasked(t *testing.T) {
+	si := PosterizeWith(disc(40, BrightRed, color.Transparent), color.Palette{BrightRed}, 4, 4, StudsOut, PosterizeOptions{Mask: Mask{Alpha: 0.5}})
+	plan, err := CreateGridMosaic(si, GreedySolve, MosaicOptions{Bricks: []Brick{OneByOnePlate}})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	w := WriterRenderer{}
+	lines := strings.Split(w.Render(plan), "\n")
+	for row, line := range lines[:4] {
+		corner := row == 0 || row == 3
+		if got := strings.HasPrefix(line, "    ") && strings.HasSuffix(line, "    "); got != corner {
+			t.Errorf("for row %d wanted blank corners %v got %q", row, corner, line)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package BrickMosaic
+
+// The min cost solvers reuse the greedy placement strategies, but rather than trusting the order
+// of the pieces they are handed, they consider the pieces that cover the grid most cheaply first.
+
+// costPerCell returns how much it costs, in cents, to cover a single cell of the grid with the
+// given piece.
+func costPerCell(p MosaicPiece) float64 {
+	return float64(p.ApproximateCost()) / float64(len(p.Extent()))
+}
+
+// sortByCostPerCell returns a copy of pieces, cheapest coverage first. The input is not modified.
+func sortByCostPerCell(pieces []MosaicPiece) []MosaicPiece {
+	sorted := make([]MosaicPiece, len(pieces))
+	copy(sorted, pieces)
+	ByCostPerCell.Sort(sorted)
+	return sorted
+}
+
+// GreedyMinCostSolve fills the grid using the same top to bottom, left to right strategy as
+// GreedySolve, but always tries the piece with the lowest cost per covered cell first.
+func GreedyMinCostSolve(g *Grid, pieces []MosaicPiece) (Solution, error) {
+	return GreedySolve(g, sortByCostPerCell(pieces))
+}
+
+// SymmetricalGreedyMinCostSolve fills the grid using the same strategy as SymmetricalGreedySolve,
+// but always tries the piece with the lowest cost per covered cell first.
+func SymmetricalGreedyMinCostSolve(g *Grid, pieces []MosaicPiece) (Solution, error) {
+	return SymmetricalGreedySolve(g, sortByCostPerCell(pieces))
+}
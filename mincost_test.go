@@ -0,0 +1,87 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"testing"
+)
+
+var oneByTwo = StudsOutPiece(OneByTwo)
+
+func TestGreedyMinCostSolve(t *testing.T) {
+	for _, test := range []struct {
+		solveTest
+		cost int
+	}{
+		{
+			solveTest{
+				"cannot be solved - no pieces",
+				WithState(1, 1, ToBeFilled),
+				[]MosaicPiece{},
+				make(map[Location]MosaicPiece),
+				true,
+			},
+			0,
+		},
+		{
+			solveTest{
+				"cheapest per cell first, regardless of list order",
+				WithState(1, 4, ToBeFilled),
+				[]MosaicPiece{oneByOne, oneByFour, oneByTwo},
+				map[Location]MosaicPiece{
+					Location{0, 0}: oneByTwo,
+					Location{0, 2}: oneByTwo,
+				},
+				false,
+			},
+			4,
+		},
+		{
+			solveTest{
+				"ties broken by size",
+				WithState(2, 2, ToBeFilled),
+				[]MosaicPiece{oneByOne, oneByTwo, twoByTwo},
+				map[Location]MosaicPiece{
+					Location{0, 0}: twoByTwo,
+				},
+				false,
+			},
+			4,
+		},
+	} {
+		got, err := GreedyMinCostSolve(&test.g, test.p)
+		if err != nil && !test.hasErr {
+			t.Errorf("for %q wanted no error got %v", test.name, err)
+		} else if err == nil && test.hasErr {
+			t.Errorf("for %q should have had an error", test.name)
+		}
+		if !reflect.DeepEqual(got.Pieces, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got.Pieces)
+		}
+		if got.Cost() != test.cost {
+			t.Errorf("for %q wanted cost %d got %d", test.name, test.cost, got.Cost())
+		}
+	}
+}
+
+func TestSymmetricalGreedyMinCostSolve(t *testing.T) {
+	g := WithState(1, 4, ToBeFilled)
+	got, err := SymmetricalGreedyMinCostSolve(&g, []MosaicPiece{oneByOne, oneByFour, oneByTwo})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	want := map[Location]MosaicPiece{
+		Location{0, 0}: oneByTwo,
+		Location{0, 2}: oneByTwo,
+	}
+	if !reflect.DeepEqual(got.Pieces, want) {
+		t.Errorf("wanted %v got %v", want, got.Pieces)
+	}
+}
+
+func TestSortByCostPerCellDoesNotModify(t *testing.T) {
+	pieces := []MosaicPiece{oneByOne, oneByFour, oneByTwo}
+	sortByCostPerCell(pieces)
+	if want := []MosaicPiece{oneByOne, oneByFour, oneByTwo}; !reflect.DeepEqual(pieces, want) {
+		t.Errorf("input was modified; wanted %v got %v", want, pieces)
+	}
+}
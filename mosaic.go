@@ -1,5 +1,14 @@
 package BrickMosaic
 
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
 // Ideal is the idealized grid of how the mosaic should look. Basically a 2d grid of color.
 type Ideal interface {
 	Orientation() ViewOrientation
@@ -8,6 +17,19 @@ type Ideal interface {
 	Color(row, col int) BrickColor
 }
 
+// MaskedIdeal is an Ideal in which some cells are left out of the mosaic altogether, so that the
+// mosaic need not be a rectangle. Masked cells are not covered by any brick.
+type MaskedIdeal interface {
+	Ideal
+	Masked(row, col int) bool
+}
+
+// isMasked determines whether the cell of the ideal is left out of the mosaic.
+func isMasked(i Ideal, row, col int) bool {
+	m, ok := i.(MaskedIdeal)
+	return ok && m.Masked(row, col)
+}
+
 // PlacedBrick represents a physical brick placed within the mosaic, at a certain location,
 // with a certain color, orientation, and shape.
 type PlacedBrick struct {
@@ -24,12 +46,46 @@ type PlacedBrick struct {
 	Shape Brick
 	// Orientation represents how the brick is placed in the mosaic
 	Orientation ViewOrientation
+	// Rotated is true if the brick is turned a quarter turn from the way it normally faces in the
+	// orientation. It is still the same physical part.
+	Rotated bool
+	// Layer is the part of the build the brick belongs to.
+	Layer Layer
 }
 
 func (p PlacedBrick) Extent() []Location {
 	return p.Locs
 }
 
+// Layer represents a part of the build. The mosaic itself is what is seen from the front; the other
+// layers hold the pieces it is built on or surrounded by.
+type Layer int
+
+const (
+	// MosaicLayer holds the pieces that make up the picture.
+	MosaicLayer Layer = iota
+	// BaseplateLayer holds the baseplates a StudsOut mosaic is built on.
+	BaseplateLayer
+	// FrameLayer holds the frame around the mosaic.
+	FrameLayer
+	// BackingLayer holds the hidden pieces behind the mosaic that hold it together.
+	BackingLayer
+)
+
+func (l Layer) String() string {
+	switch l {
+	case MosaicLayer:
+		return "mosaic"
+	case BaseplateLayer:
+		return "baseplate"
+	case FrameLayer:
+		return "frame"
+	case BackingLayer:
+		return "backing"
+	}
+	return fmt.Sprintf("Layer(%d)", int(l))
+}
+
 // ViewOrientation represents the orientation of each brick in the mosaic.
 type ViewOrientation int
 
@@ -53,6 +109,54 @@ type Plan interface {
 	Pieces() []PlacedBrick
 	Piece(row, col int) PlacedBrick
 	Inventory() Inventory
+	// Shortfall lists the parts that are needed to finish the plan, but that were not available.
+	Shortfall() Inventory
+}
+
+// LayeredPlan is a Plan with more to it than the mosaic. Pieces only returns the mosaic's own
+// pieces, while the Inventory includes every layer.
+type LayeredPlan interface {
+	Plan
+	// Layer returns the pieces of a layer, e.g. the baseplates, in the order they are numbered.
+	Layer(l Layer) []PlacedBrick
+	// LayerInventory lists the parts in a single layer.
+	LayerInventory(l Layer) Inventory
+}
+
+// LayerPlan returns a Plan whose pieces are those of a single layer of the plan, e.g. to render
+// the backing on its own.
+func LayerPlan(p LayeredPlan, l Layer) Plan {
+	return layerPlan{p, l}
+}
+
+type layerPlan struct {
+	plan  LayeredPlan
+	layer Layer
+}
+
+func (l layerPlan) Orig() Ideal {
+	return l.plan.Orig()
+}
+
+func (l layerPlan) Pieces() []PlacedBrick {
+	return l.plan.Layer(l.layer)
+}
+
+func (l layerPlan) Piece(row, col int) PlacedBrick {
+	for _, p := range l.Pieces() {
+		if p.Origin == (Location{row, col}) {
+			return p
+		}
+	}
+	return PlacedBrick{}
+}
+
+func (l layerPlan) Inventory() Inventory {
+	return l.plan.LayerInventory(l.layer)
+}
+
+func (l layerPlan) Shortfall() Inventory {
+	return MakeInventory()
 }
 
 // Create is the interface by which we convert Ideal mosaics into a plan
@@ -67,6 +171,20 @@ type gridBasedPlan struct {
 	orientation  ViewOrientation
 	solutions    map[BrickColor]Solution
 	placedBricks map[Location]PlacedBrick
+	shortfall    Inventory
+	prices       *PriceTable
+	// partsForColor holds the parts each color was solved with.
+	partsForColor map[BrickColor][]Brick
+	// layers holds the pieces of every layer but the mosaic.
+	layers map[Layer][]PlacedBrick
+	// sectionSize is the size of the sections no piece may straddle, or 0 if there are none.
+	sectionSize int
+	// backing is the solution for the BackingLayer, solved in backingColor with backingPieces.
+	backing       Solution
+	backingColor  BrickColor
+	backingPieces []MosaicPiece
+	// stock is the stock the plan was built from, if any.
+	stock *Stock
 }
 
 func (g *gridBasedPlan) Orig() Ideal {
@@ -78,6 +196,7 @@ func (g *gridBasedPlan) Pieces() []PlacedBrick {
 	for _, b := range g.placedBricks {
 		bricks = append(bricks, b)
 	}
+	sort.Sort(placedRowMajor(bricks))
 	return bricks
 }
 
@@ -87,29 +206,380 @@ func (g *gridBasedPlan) Piece(row, col int) PlacedBrick {
 
 func (g *gridBasedPlan) Inventory() Inventory {
 	i := MakeInventory()
+	i.SetPrices(g.prices)
 	for _, p := range g.Pieces() {
 		i.Add(p.Color, p.Shape)
 	}
+	for _, l := range sortedLayers(g.layers) {
+		for _, p := range g.layers[l] {
+			i.Add(p.Color, p.Shape)
+		}
+	}
+	return i
+}
+
+func (g *gridBasedPlan) Layer(l Layer) []PlacedBrick {
+	if l == MosaicLayer {
+		return g.Pieces()
+	}
+	return g.layers[l]
+}
+
+// setBacking records the backing solution and places its pieces in the BackingLayer.
+func (g *gridBasedPlan) setBacking(s Solution, c BrickColor, pieces []MosaicPiece) {
+	g.backing = s
+	g.backingColor = c
+	g.backingPieces = pieces
+	g.layers[BackingLayer] = placeBacking(s, c, g.orientation)
+}
+
+func (g *gridBasedPlan) LayerInventory(l Layer) Inventory {
+	i := MakeInventory()
+	i.SetPrices(g.prices)
+	for _, p := range g.Layer(l) {
+		i.Add(p.Color, p.Shape)
+	}
 	return i
 }
 
+// sortedLayers returns the layers in order.
+func sortedLayers(layers map[Layer][]PlacedBrick) []Layer {
+	var result []Layer
+	for l := range layers {
+		result = append(result, l)
+	}
+	sort.Sort(byLayer(result))
+	return result
+}
+
+type byLayer []Layer
+
+func (b byLayer) Len() int {
+	return len(b)
+}
+
+func (b byLayer) Less(i, j int) bool {
+	return b[i] < b[j]
+}
+
+func (b byLayer) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (g *gridBasedPlan) Shortfall() Inventory {
+	return g.shortfall
+}
+
+// MosaicOptions configures how CreateGridMosaic builds its plan. The zero value uses an unlimited
+// supply of every standard brick.
+type MosaicOptions struct {
+	// Stock, if set, limits the parts that can be used. Solvers fall back to smaller parts when a
+	// part runs out, and whatever cannot be covered is reported in the plan's Shortfall.
+	Stock *Stock
+	// Workers is the number of colors solved at the same time. If 0, one per CPU.
+	Workers int
+	// Bricks are the parts the mosaic may be built from, e.g. from LoadCatalog or SelectParts. If
+	// nil, the standard Pieces are used.
+	Bricks []Brick
+	// PartsForColor further restricts the parts used in particular colors, e.g. to the sizes that
+	// are made in that color. Colors that are not in the map may use any of the Bricks.
+	PartsForColor map[BrickColor][]Brick
+	// Availability, if set, leaves out the parts that were never made in a color, e.g. the
+	// DefaultAvailability. If that leaves a color without a 1x1 part to fill the gaps with, though
+	// its parts had one, no plan is made.
+	Availability *Availability
+	// Order, if set, sorts the pieces of every color before they are handed to the solver. If nil,
+	// the pieces are in the order of the Bricks.
+	Order By
+	// Prices, if set, gives the price of parts in particular colors. The cost-aware solvers then
+	// pick the parts that are cheapest in each color, and the plan's Inventory is priced with it.
+	Prices *PriceTable
+	// Baseplate, if set, is the baseplate a StudsOut mosaic is built on, e.g. Baseplate32. The
+	// plan includes one for every section of the mosaic, and unless BondBaseplates is set, no
+	// piece straddles two of them.
+	Baseplate Brick
+	// BaseplateColor is the color of the baseplates. If not set, they are MediumStoneGrey.
+	BaseplateColor BrickColor
+	// BondBaseplates lets pieces straddle the seams between baseplates, which ties them together
+	// but means the sections cannot be built separately.
+	BondBaseplates bool
+	// Frame, if set, is built around the mosaic from the same parts and with the same solver.
+	Frame *Frame
+	// Backing, if set, is built behind the mosaic to hold it together. It is made from the same
+	// parts, but only the plates for a StudsOut mosaic.
+	Backing *Backing
+}
+
+// ColorError is the error from solving the grid of a single color.
+type ColorError struct {
+	Color BrickColor
+	Err   error
+}
+
+func (e ColorError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Color.name, e.Err)
+}
+
+// SolveErrors holds the error of every color that could not be solved completely.
+type SolveErrors []ColorError
+
+func (e SolveErrors) Error() string {
+	var msgs []string
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
 // CreateGridMosaic converts an Ideal representation of the mosaic into a plan for building
 // the mosaic. In other words, it picks the pieces to use and where to place them according
 // to the logic in the GridSolver implementation.
-func CreateGridMosaic(m Ideal, solver GridSolver) Plan {
+//
+// If any color has no 1x1 part it may use, there is no plan and an error names the colors.
+// If the solver fails to fill the grid of any color, the plan is still returned, with holes
+// where the failures were, along with a SolveErrors describing them. ValidatePlan lists the holes.
+func CreateGridMosaic(m Ideal, solver GridSolver, opts MosaicOptions) (Plan, error) {
+	return CreateGridMosaicContext(context.Background(), m, solver, opts)
+}
+
+// CreateGridMosaicContext is like CreateGridMosaic, but stops solving once the context is done, in
+// which case it returns no plan and the context's error. Each color is solved independently, on up to
+// opts.Workers goroutines at once, so the solver must be safe to call concurrently. The plan does
+// not depend on the order the colors happen to finish in.
+//
+// It returns as soon as the context is done, even if a solver is still running. A solver that
+// knows nothing of the context keeps running in the background until it finishes on its own; to
+// have it stop as well, pass a ContextSolver bound to the same context, e.g.
+//
+//	CreateGridMosaicContext(ctx, m, ContextSolver(ExactMinCostSolveContext).Bind(ctx), opts)
+func CreateGridMosaicContext(ctx context.Context, m Ideal, solver GridSolver, opts MosaicOptions) (Plan, error) {
+	if opts.Baseplate != nil && m.Orientation() != StudsOut {
+		return nil, fmt.Errorf("baseplates can only be used for StudsOut mosaics")
+	}
+	if opts.Frame != nil {
+		if err := opts.Frame.check(m.Orientation()); err != nil {
+			return nil, err
+		}
+	}
 	grids := makeGrids(m)
+	// The frame does not sit on the baseplates, so it is solved without sections.
+	frameSolver := solver
+	sectionSize := 0
+	if opts.Baseplate != nil && !opts.BondBaseplates {
+		sectionSize = opts.Baseplate.Length()
+		solver = SectionSolver(solver, sectionSize)
+	}
+
+	bricks := opts.Bricks
+	if bricks == nil {
+		bricks = allBricks()
+	}
+	var colors []BrickColor
+	for color := range grids {
+		colors = append(colors, color)
+	}
+	sort.Sort(byColorId(colors))
+
+	// Work out which parts each color may use before solving anything, so that a color that can
+	// never be finished is caught straight away.
+	partsForColor := make(map[BrickColor][]Brick)
+	colorPieces := make([][]MosaicPiece, len(colors))
+	var unfillable []string
+	for i, color := range colors {
+		if opts.lacksFiller(color, m.Orientation(), opts.chosenParts(color, bricks)) {
+			unfillable = append(unfillable, color.name)
+		}
+		parts := opts.partsFor(color, bricks)
+		partsForColor[color] = parts
+		colorPieces[i] = opts.piecesFor(color, m.Orientation(), parts)
+	}
+	var framePieces []MosaicPiece
+	if opts.Frame != nil {
+		if opts.lacksFiller(opts.Frame.Color, m.Orientation(), opts.chosenParts(opts.Frame.Color, bricks)) {
+			unfillable = append(unfillable, opts.Frame.Color.name+" (frame)")
+		}
+		framePieces = opts.piecesFor(opts.Frame.Color, m.Orientation(), opts.partsFor(opts.Frame.Color, bricks))
+	}
+	var backingPieces []MosaicPiece
+	if opts.Backing != nil {
+		if opts.lacksFiller(opts.Backing.Color, m.Orientation(), backingParts(m.Orientation(), opts.chosenParts(opts.Backing.Color, bricks))) {
+			unfillable = append(unfillable, opts.Backing.Color.name+" (backing)")
+		}
+		parts := backingParts(m.Orientation(), opts.partsFor(opts.Backing.Color, bricks))
+		backingPieces = opts.piecesFor(opts.Backing.Color, m.Orientation(), parts)
+	}
+	if len(unfillable) > 0 {
+		return nil, fmt.Errorf("no 1x1 part is available in %v", strings.Join(unfillable, ", "))
+	}
+
+	type result struct {
+		solution Solution
+		missing  []Brick
+		err      error
+	}
+	// Each worker writes only to the results of the colors it solves.
+	results := make([]result, len(colors))
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(colors); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if ctx.Err() != nil {
+					continue
+				}
+				// A solver that does not watch the context is left to finish on its own once the
+				// context is done; its result is thrown away. Give it a grid of its own so that it
+				// does not write to one the caller can see.
+				original := grids[colors[i]]
+				grid := original.Clone()
+				pieces := colorPieces[i]
+				done := make(chan result, 1)
+				go func(c BrickColor) {
+					var r result
+					if opts.Stock == nil {
+						r.solution, r.err = solver(&grid, pieces)
+					} else {
+						r.solution, r.missing, r.err = solveWithStock(ctx, &grid, solver, pieces, c, opts.Stock)
+					}
+					done <- r
+				}(colors[i])
+				select {
+				case results[i] = <-done:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+feed:
+	for i := range colors {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	// TODO(ndunn): how do I inject which pieces are allowed?
-	allPieces := PiecesForOrientation(m.Orientation(), allBricks())
 	solutions := make(map[BrickColor]Solution)
-	placedBricks := make(map[Location]PlacedBrick)
-	for color, grid := range grids {
-		solution, _ := solver(&grid, allPieces)
-		solutions[color] = solution
+	shortfall := MakeInventory()
+	var errs SolveErrors
+	for i, color := range colors {
+		solutions[color] = results[i].solution
+		for _, b := range results[i].missing {
+			shortfall.Add(color, b)
+		}
+		if results[i].err != nil {
+			errs = append(errs, ColorError{color, results[i].err})
+		}
+	}
+	plan := newGridBasedPlan(m, grids, solutions)
+	plan.prices = opts.Prices
+	plan.partsForColor = partsForColor
+	plan.sectionSize = sectionSize
+	plan.stock = opts.Stock
+	if opts.Baseplate != nil {
+		c := opts.BaseplateColor
+		if c == (BrickColor{}) {
+			c = MediumStoneGrey
+		}
+		plan.layers[BaseplateLayer] = baseplateSections(m.NumRows(), m.NumCols(), opts.Baseplate, c)
+	}
+	// The frame and backing use what the mosaic left of the stock in their colors.
+	if opts.Frame != nil {
+		stock := opts.Stock
+		if stock != nil {
+			stock = stock.remaining(opts.Frame.Color, plan.Pieces())
+		}
+		frame, missing, err := opts.Frame.build(ctx, m, frameSolver, framePieces, stock)
+		plan.layers[FrameLayer] = frame
+		for _, b := range missing {
+			shortfall.Add(opts.Frame.Color, b)
+		}
+		if err != nil {
+			errs = append(errs, ColorError{opts.Frame.Color, fmt.Errorf("frame: %v", err)})
+		}
+	}
+	if opts.Backing != nil {
+		stock := opts.Stock
+		if stock != nil {
+			stock = stock.remaining(opts.Backing.Color, append(plan.Pieces(), plan.layers[FrameLayer]...))
+		}
+		backing, missing, err := opts.Backing.build(ctx, plan, backingPieces, stock)
+		plan.setBacking(backing, opts.Backing.Color, backingPieces)
+		for _, b := range missing {
+			shortfall.Add(opts.Backing.Color, b)
+		}
+		if err != nil {
+			errs = append(errs, ColorError{opts.Backing.Color, fmt.Errorf("backing: %v", err)})
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	shortfall.SetPrices(opts.Prices)
+	plan.shortfall = shortfall
+	if len(errs) > 0 {
+		return plan, errs
+	}
+	return plan, nil
+}
 
+// partsFor returns the parts the color may be built from, out of the bricks.
+func (opts MosaicOptions) partsFor(color BrickColor, bricks []Brick) []Brick {
+	parts := opts.chosenParts(color, bricks)
+	if opts.Availability != nil {
+		parts = opts.Availability.Parts(color, parts)
+	}
+	return parts
+}
+
+// chosenParts returns the parts chosen for the color out of the bricks, whether or not they were
+// made in it.
+func (opts MosaicOptions) chosenParts(color BrickColor, bricks []Brick) []Brick {
+	if p, ok := opts.PartsForColor[color]; ok {
+		return p
+	}
+	return bricks
+}
+
+// lacksFiller determines whether the Availability leaves the color without a piece that fills a
+// single cell, though the parts chosen for it have one. If the parts never had one, the solver
+// fills what it can, as it does without an Availability.
+func (opts MosaicOptions) lacksFiller(color BrickColor, o ViewOrientation, parts []Brick) bool {
+	if opts.Availability == nil || !hasFiller(PiecesForOrientation(o, parts)) {
+		return false
+	}
+	return !hasFiller(PiecesForOrientation(o, opts.Availability.Parts(color, parts)))
+}
+
+// piecesFor returns the pieces the solver is given for the color, made from the parts.
+func (opts MosaicOptions) piecesFor(color BrickColor, o ViewOrientation, parts []Brick) []MosaicPiece {
+	pieces := opts.Prices.PiecesForColor(color, PiecesForOrientation(o, parts))
+	if opts.Order != nil {
+		opts.Order.Sort(pieces)
+	}
+	return pieces
+}
+
+// newGridBasedPlan creates the plan for the ideal from the solution to each color's grid.
+func newGridBasedPlan(m Ideal, grids map[BrickColor]Grid, solutions map[BrickColor]Solution) *gridBasedPlan {
+	placedBricks := make(map[Location]PlacedBrick)
+	for _, color := range sortedColors(solutions) {
+		solution := solutions[color]
 		// Now we know where each piece goes. Create PlacedBrick representations of the pieces.
 		counter := 0
-		for loc, piece := range solution.Pieces {
+		for _, loc := range sortedLocations(solution.Pieces) {
+			piece := solution.Pieces[loc]
 			// TODO(ndunn): do we really need Brick, Piece, MosaicPiece, and PlacedBrick?
 			pb := PlacedBrick{
 				Id:          counter,
@@ -118,17 +588,20 @@ func CreateGridMosaic(m Ideal, solver GridSolver) Plan {
 				Color:       color,
 				Shape:       piece,
 				Orientation: m.Orientation(),
+				Rotated:     piece.Rotated(),
 			}
 			placedBricks[loc] = pb
 			counter++
 		}
 	}
 	return &gridBasedPlan{
-		m,
-		grids,
-		m.Orientation(),
-		solutions,
-		placedBricks,
+		img:          m,
+		colorGrid:    grids,
+		orientation:  m.Orientation(),
+		solutions:    solutions,
+		placedBricks: placedBricks,
+		shortfall:    MakeInventory(),
+		layers:       make(map[Layer][]PlacedBrick),
 	}
 }
 
@@ -152,11 +625,14 @@ func CreateGridMosaic(m Ideal, solver GridSolver) Plan {
 // 0 0 0 0
 // 0 0 1 1
 //
-// Where the 0's indicate Empty and 1 represents ToBeFilled.
+// Where the 0's indicate Empty and 1 represents ToBeFilled. Masked cells are Empty in every grid.
 func makeGrids(i Ideal) map[BrickColor]Grid {
 	grids := make(map[BrickColor]Grid)
 	for row := 0; row < i.NumRows(); row++ {
 		for col := 0; col < i.NumCols(); col++ {
+			if isMasked(i, row, col) {
+				continue
+			}
 			color := i.Color(row, col)
 			// New color - initialize the grid
 			if _, ok := grids[color]; !ok {
@@ -1,5 +1,12 @@
 package BrickMosaic
 
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
 /*
 import (
 	"reflect"
@@ -58,3 +65,113 @@ func TestMakeGrids(t *testing.T) {
 
 }
 */
+
+// stripes is an Ideal whose columns cycle through the colors.
+type stripes struct {
+	rows, cols int
+	colors     []BrickColor
+}
+
+func (s stripes) Orientation() ViewOrientation {
+	return StudsTop
+}
+func (s stripes) NumRows() int {
+	return s.rows
+}
+func (s stripes) NumCols() int {
+	return s.cols
+}
+func (s stripes) Color(row, col int) BrickColor {
+	return s.colors[(col/3)%len(s.colors)]
+}
+
+func TestCreateGridMosaicContextIsDeterministic(t *testing.T) {
+	ideal := stripes{12, 30, []BrickColor{BrightRed, Black, White, BrightBlue, BrightYellow}}
+	want, err := CreateGridMosaicContext(context.Background(), ideal, GreedySolve, MosaicOptions{Workers: 1})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	for _, workers := range []int{0, 2, 8} {
+		got, err := CreateGridMosaicContext(context.Background(), ideal, GreedySolve, MosaicOptions{Workers: workers})
+		if err != nil {
+			t.Fatalf("for %d workers wanted no error got %v", workers, err)
+		}
+		if !reflect.DeepEqual(got.Pieces(), want.Pieces()) {
+			t.Errorf("for %d workers wanted %v got %v", workers, want.Pieces(), got.Pieces())
+		}
+	}
+}
+
+func TestCreateGridMosaicContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	solved := 0
+	solver := func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+		solved++
+		return GreedySolve(g, pieces)
+	}
+	ideal := stripes{4, 12, []BrickColor{BrightRed, Black}}
+	if _, err := CreateGridMosaicContext(ctx, ideal, solver, MosaicOptions{Workers: 1}); err != context.Canceled {
+		t.Errorf("wanted %v got %v", context.Canceled, err)
+	}
+	if solved != 0 {
+		t.Errorf("wanted no colors solved after cancellation, got %d", solved)
+	}
+}
+
+func TestCreateGridMosaicContextCancelledDuringSolve(t *testing.T) {
+	ideal := stripes{30, 60, []BrickColor{BrightRed, Black}}
+	release := make(chan struct{})
+	defer close(release)
+	// slow knows nothing of the context; it only finishes once the test is over.
+	slow := func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+		<-release
+		return GreedySolve(g, pieces)
+	}
+	for _, test := range []struct {
+		name   string
+		solver func(ctx context.Context) GridSolver
+	}{
+		{"plain solver", func(ctx context.Context) GridSolver { return slow }},
+		{"anytime solver", func(ctx context.Context) GridSolver {
+			return AnytimeContextSolver(time.Minute, nil).Bind(ctx)
+		}},
+		{"exact solver", func(ctx context.Context) GridSolver {
+			return ContextSolver(ExactMinCostSolveContext).Bind(ctx)
+		}},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+		start := time.Now()
+		_, err := CreateGridMosaicContext(ctx, ideal, test.solver(ctx), MosaicOptions{Workers: 2})
+		if err != context.Canceled {
+			t.Errorf("for %q wanted %v got %v", test.name, context.Canceled, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("for %q wanted to return promptly after cancellation, took %v", test.name, elapsed)
+		}
+		cancel()
+	}
+}
+
+func TestContextSolversStop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for name, solver := range map[string]ContextSolver{
+		"exact":   ExactMinCostSolveContext,
+		"anytime": AnytimeContextSolver(time.Minute, nil),
+	} {
+		g := NewGrid(12, 12)
+		for row := 0; row < 12; row++ {
+			for col := 0; col < 12; col++ {
+				g.Set(row, col, ToBeFilled)
+			}
+		}
+		if _, err := solver(ctx, &g, PiecesForOrientation(StudsOut, allBricks())); err != context.Canceled {
+			t.Errorf("for %q wanted %v got %v", name, context.Canceled, err)
+		}
+	}
+}
@@ -0,0 +1,589 @@
+package BrickMosaic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// The optimizer improves an existing Solution with local search. Every move takes a few
+// neighboring pieces out of the solution and covers exactly the same cells with different pieces,
+// so the solution never loses coverage:
+//
+// merge: two neighboring pieces are replaced by a single piece with the shape of both combined,
+// e.g. two 1x2s side by side become a 1x4.
+//
+// split: a piece is replaced by two or more smaller pieces.
+//
+// swap: a piece and up to two of its neighbors are replaced by a different arrangement of pieces.
+//
+// Moves that improve the objective are always accepted. With a temperature above zero, the
+// optimizer anneals: worse moves are accepted with a probability that shrinks as the temperature
+// cools, which lets it escape local minima. With a temperature of zero it is a hill climber. All
+// of the randomness comes from the seed, so the same inputs always give the same result.
+
+// Objective is what the optimizer minimizes.
+type Objective int
+
+const (
+	// CostObjective minimizes the approximate cost of the pieces.
+	CostObjective Objective = iota
+	// PieceCountObjective minimizes the number of pieces.
+	PieceCountObjective
+	// SeamObjective minimizes the number of seams that line up with the seam beneath them.
+	SeamObjective
+)
+
+func (o Objective) String() string {
+	switch o {
+	case CostObjective:
+		return "cost"
+	case PieceCountObjective:
+		return "pieces"
+	case SeamObjective:
+		return "seams"
+	}
+	return fmt.Sprintf("Objective(%d)", int(o))
+}
+
+// ObjectiveForName returns the objective whose String matches name.
+func ObjectiveForName(name string) (Objective, error) {
+	for _, o := range []Objective{CostObjective, PieceCountObjective, SeamObjective} {
+		if o.String() == name {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown objective %q; wanted one of cost, pieces or seams", name)
+}
+
+// OptimizeOptions configures the optimizer.
+type OptimizeOptions struct {
+	Objective Objective
+	// Orientation of the mosaic; the seam objective depends on which way the courses run.
+	Orientation ViewOrientation
+	// Iterations is the number of moves to attempt. If 0, ten moves per piece are attempted.
+	Iterations int
+	// Temperature is the starting temperature for annealing, in units of the objective. It cools
+	// linearly to zero. A temperature of 0 only ever accepts moves that do not make things worse.
+	Temperature float64
+	// Seed for the random number generator.
+	Seed int64
+	// Stock, if set, limits OptimizePlan to the parts in stock: a move that would use more of a
+	// part in some color than the stock has left, once the plan's other layers are taken out of it,
+	// is rejected. If nil, OptimizePlan uses the stock the plan was created with, if any.
+	Stock *Stock
+}
+
+// OptimizeSolution improves the solution with local search, using only the given pieces. The
+// returned solution covers exactly the same cells as the original, and is never worse according
+// to the objective.
+func OptimizeSolution(s Solution, pieces []MosaicPiece, opts OptimizeOptions) Solution {
+	return optimizeSolution(s, pieces, opts, nil)
+}
+
+// optimizeSolution is like OptimizeSolution, but rejects moves that would use more of a part than
+// the limit allows. A nil limit allows any number of every part.
+func optimizeSolution(s Solution, pieces []MosaicPiece, opts OptimizeOptions, limit map[Brick]int) Solution {
+	o := newOptimizer(s, pieces, opts)
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = 10 * len(s.Pieces)
+	}
+	current := o.score()
+	bestEnergy := current
+	// The moves accepted since the best solution so far, to be undone at the end.
+	var sinceBest []*move
+	for i := 0; i < iterations && len(o.locs) > 0; i++ {
+		m := o.propose()
+		if m == nil || !o.withinLimit(m, limit) {
+			continue
+		}
+		o.apply(m)
+		e := o.score()
+		temperature := opts.Temperature * (1 - float64(i)/float64(iterations))
+		if e <= current || (temperature > 0 && o.rng.Float64() < math.Exp((current-e)/temperature)) {
+			current = e
+			sinceBest = append(sinceBest, m)
+			if e < bestEnergy {
+				bestEnergy = e
+				sinceBest = sinceBest[:0]
+			}
+		} else {
+			o.apply(m.reverse())
+		}
+	}
+	for i := len(sinceBest) - 1; i >= 0; i-- {
+		o.apply(sinceBest[i].reverse())
+	}
+	return Solution{s.Original, o.locs}
+}
+
+// OptimizePlan optimizes the solution for every color in the plan. Only plans created by
+// CreateGridMosaic can be optimized. Each color only uses the pieces made from the parts it was
+// allowed when the plan was created, and, given a stock, no more parts than are in stock.
+func OptimizePlan(p Plan, pieces []MosaicPiece, opts OptimizeOptions) (Plan, error) {
+	g, ok := p.(*gridBasedPlan)
+	if !ok {
+		return nil, fmt.Errorf("cannot optimize plan of type %T", p)
+	}
+	stock := opts.Stock
+	if stock == nil {
+		stock = g.stock
+	}
+	var others []PlacedBrick
+	for _, l := range sortedLayers(g.layers) {
+		others = append(others, g.layers[l]...)
+	}
+	solutions := make(map[BrickColor]Solution)
+	for _, color := range sortedColors(g.solutions) {
+		colorPieces := pieces
+		if parts, ok := g.partsForColor[color]; ok {
+			colorPieces = onlyParts(pieces, parts)
+		}
+		colorPieces = g.prices.PiecesForColor(color, colorPieces)
+		var limit map[Brick]int
+		if stock != nil {
+			limit = stock.remaining(color, others).counts[color]
+			if limit == nil {
+				limit = make(map[Brick]int)
+			}
+		}
+		if g.sectionSize == 0 {
+			solutions[color] = optimizeSolution(g.solutions[color], colorPieces, opts, limit)
+		} else {
+			// Optimize each section on its own, so that no merge crosses into the next one. The
+			// sections share the stock, so each may only use what the others leave of it.
+			solution := Solution{g.solutions[color].Original, copyPieces(g.solutions[color].Pieces)}
+			for _, section := range splitSections(g.solutions[color], g.sectionSize) {
+				for loc := range section.Pieces {
+					delete(solution.Pieces, loc)
+				}
+				sectionLimit := limitWithout(limit, solution.Pieces)
+				for loc, p := range optimizeSolution(section, colorPieces, opts, sectionLimit).Pieces {
+					solution.Pieces[loc] = p
+				}
+			}
+			solutions[color] = solution
+		}
+		// Each color gets its own stream of random numbers, so that the result does not depend on
+		// the order the colors are visited in.
+		opts.Seed++
+	}
+	optimized := newGridBasedPlan(g.img, g.colorGrid, solutions)
+	optimized.shortfall = g.shortfall
+	optimized.prices = g.prices
+	optimized.partsForColor = g.partsForColor
+	optimized.layers = g.layers
+	optimized.sectionSize = g.sectionSize
+	optimized.stock = g.stock
+	if g.backingPieces != nil {
+		// The seams of the mosaic have moved, so the backing has to be laid again.
+		optimized.layers = make(map[Layer][]PlacedBrick)
+		for l, bricks := range g.layers {
+			optimized.layers[l] = bricks
+		}
+		var backingStock *Stock
+		if stock != nil {
+			var used []PlacedBrick
+			used = append(used, optimized.Pieces()...)
+			for _, l := range sortedLayers(g.layers) {
+				if l != BackingLayer {
+					used = append(used, g.layers[l]...)
+				}
+			}
+			backingStock = stock.remaining(g.backingColor, used)
+		}
+		backing, missing, err := Backing{g.backingColor}.build(context.Background(), optimized, g.backingPieces, backingStock)
+		if err != nil && stock == nil {
+			return nil, fmt.Errorf("backing: %v", err)
+		}
+		if err != nil || len(missing) > 0 {
+			// There are not enough parts to lay the backing again, but the old one still covers
+			// the same cells.
+			backing = g.backing
+		}
+		optimized.setBacking(backing, g.backingColor, g.backingPieces)
+	}
+	return optimized, nil
+}
+
+// limitWithout returns what is left of the limit once the pieces are taken out of it, or nil for
+// a nil limit.
+func limitWithout(limit map[Brick]int, pieces map[Location]MosaicPiece) map[Brick]int {
+	if limit == nil {
+		return nil
+	}
+	result := make(map[Brick]int)
+	for b, n := range limit {
+		result[b] = n
+	}
+	for b, n := range countParts(pieces) {
+		result[b] -= n
+	}
+	return result
+}
+
+// countParts counts the pieces by the part they are made from.
+func countParts(pieces map[Location]MosaicPiece) map[Brick]int {
+	counts := make(map[Brick]int)
+	for _, p := range pieces {
+		counts[BaseBrick(p)]++
+	}
+	return counts
+}
+
+// sortedColors returns the colors of the solutions ordered by id, then name.
+func sortedColors(solutions map[BrickColor]Solution) []BrickColor {
+	var colors []BrickColor
+	for c := range solutions {
+		colors = append(colors, c)
+	}
+	sort.Sort(byColorId(colors))
+	return colors
+}
+
+type byColorId []BrickColor
+
+func (b byColorId) Len() int {
+	return len(b)
+}
+
+func (b byColorId) Less(i, j int) bool {
+	if b[i].id != b[j].id {
+		return b[i].id < b[j].id
+	}
+	return b[i].name < b[j].name
+}
+
+func (b byColorId) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func copyPieces(pieces map[Location]MosaicPiece) map[Location]MosaicPiece {
+	result := make(map[Location]MosaicPiece, len(pieces))
+	for loc, p := range pieces {
+		result[loc] = p
+	}
+	return result
+}
+
+type optimizer struct {
+	original Grid
+	pieces   []MosaicPiece
+	opts     OptimizeOptions
+	rng      *rand.Rand
+	locs     map[Location]MosaicPiece
+	// shapes maps the normalized extent of a shape to the cheapest piece with that shape.
+	shapes map[string]MosaicPiece
+
+	// Everything below is kept up to date as moves are made, so that a move costs time in
+	// proportion to the cells it touches rather than to the size of the solution.
+
+	// origins holds the keys of locs, in an order that only depends on the moves made, so that a
+	// piece can be picked at random; index is the position of each origin in origins.
+	origins []Location
+	index   map[Location]int
+	// owner maps every covered cell to the origin of the piece covering it.
+	owner map[Location]Location
+	// cost is the cost of the pieces, and counts the number of each part.
+	cost   int
+	counts map[Brick]int
+	seams  *seamTracker
+	// scratch is the grid retile covers cells in. Between moves, every cell of it is Empty.
+	scratch Grid
+}
+
+// move replaces the pieces at the removed origins with the added ones, which cover the same cells.
+type move struct {
+	removed, added map[Location]MosaicPiece
+}
+
+// reverse returns the move that undoes m.
+func (m *move) reverse() *move {
+	return &move{m.added, m.removed}
+}
+
+func newOptimizer(s Solution, pieces []MosaicPiece, opts OptimizeOptions) *optimizer {
+	o := &optimizer{
+		original: s.Original,
+		pieces:   pieces,
+		opts:     opts,
+		rng:      rand.New(rand.NewSource(opts.Seed)),
+		locs:     make(map[Location]MosaicPiece),
+		shapes:   make(map[string]MosaicPiece),
+		index:    make(map[Location]int),
+		owner:    make(map[Location]Location),
+		counts:   make(map[Brick]int),
+		scratch:  NewGrid(s.Original.Rows, s.Original.Cols),
+	}
+	for _, p := range pieces {
+		key, _ := shapeKey(p.Extent())
+		if existing, ok := o.shapes[key]; !ok || p.ApproximateCost() < existing.ApproximateCost() {
+			o.shapes[key] = p
+		}
+	}
+	for _, origin := range sortedLocations(s.Pieces) {
+		o.put(origin, s.Pieces[origin])
+	}
+	if opts.Objective == SeamObjective {
+		o.seams = newSeamTracker(s, opts.Orientation)
+	}
+	return o
+}
+
+// energy is the value of the objective for the given pieces. Cost breaks ties between solutions
+// that are otherwise equally good.
+func (o *optimizer) energy(pieces map[Location]MosaicPiece) float64 {
+	s := Solution{o.original, pieces}
+	tieBreak := float64(s.Cost()) / 1e6
+	switch o.opts.Objective {
+	case PieceCountObjective:
+		return float64(len(pieces)) + tieBreak
+	case SeamObjective:
+		return float64(alignedSeams(s, o.opts.Orientation)) + tieBreak
+	}
+	return float64(s.Cost())
+}
+
+// score is the energy of the current pieces, from what has been kept up to date.
+func (o *optimizer) score() float64 {
+	tieBreak := float64(o.cost) / 1e6
+	switch o.opts.Objective {
+	case PieceCountObjective:
+		return float64(len(o.locs)) + tieBreak
+	case SeamObjective:
+		return float64(o.seams.aligned()) + tieBreak
+	}
+	return float64(o.cost)
+}
+
+// apply makes the move.
+func (o *optimizer) apply(m *move) {
+	o.seams.replace(m.removed, m.added)
+	for _, origin := range sortedLocations(m.removed) {
+		o.take(origin)
+	}
+	for _, origin := range sortedLocations(m.added) {
+		o.put(origin, m.added[origin])
+	}
+}
+
+// put adds the piece at origin.
+func (o *optimizer) put(origin Location, p MosaicPiece) {
+	o.locs[origin] = p
+	o.index[origin] = len(o.origins)
+	o.origins = append(o.origins, origin)
+	for _, rel := range p.Extent() {
+		o.owner[origin.Add(rel)] = origin
+	}
+	o.cost += p.ApproximateCost()
+	o.counts[BaseBrick(p)]++
+}
+
+// take removes the piece at origin.
+func (o *optimizer) take(origin Location) {
+	p := o.locs[origin]
+	delete(o.locs, origin)
+	// Move the last origin into the place of the one removed.
+	i, last := o.index[origin], o.origins[len(o.origins)-1]
+	o.origins[i] = last
+	o.index[last] = i
+	o.origins = o.origins[:len(o.origins)-1]
+	delete(o.index, origin)
+	for _, rel := range p.Extent() {
+		delete(o.owner, origin.Add(rel))
+	}
+	o.cost -= p.ApproximateCost()
+	o.counts[BaseBrick(p)]--
+}
+
+// withinLimit determines whether the move leaves no more of any part than the limit allows, or at
+// least no more than there is already. A nil limit allows anything.
+func (o *optimizer) withinLimit(m *move, limit map[Brick]int) bool {
+	if limit == nil {
+		return true
+	}
+	delta := make(map[Brick]int)
+	for _, p := range m.removed {
+		delta[BaseBrick(p)]--
+	}
+	for _, p := range m.added {
+		delta[BaseBrick(p)]++
+	}
+	for b, d := range delta {
+		if d > 0 && o.counts[b]+d > limit[b] {
+			return false
+		}
+	}
+	return true
+}
+
+// shapeKey returns a key that is the same for any two extents of the same shape, along with the
+// upper left corner of the extent's bounding box.
+func shapeKey(extent []Location) (string, Location) {
+	if len(extent) == 0 {
+		return "", Location{}
+	}
+	min := extent[0]
+	for _, loc := range extent {
+		if loc.Row < min.Row {
+			min.Row = loc.Row
+		}
+		if loc.Col < min.Col {
+			min.Col = loc.Col
+		}
+	}
+	normalized := make([]Location, len(extent))
+	for i, loc := range extent {
+		normalized[i] = Location{loc.Row - min.Row, loc.Col - min.Col}
+	}
+	sort.Sort(rowMajor(normalized))
+	return fmt.Sprint(normalized), min
+}
+
+// neighbors returns the origins of the pieces sharing an edge with the piece at origin.
+func (o *optimizer) neighbors(origin Location) []Location {
+	seen := make(map[Location]bool)
+	var result []Location
+	for _, cell := range o.cells(origin) {
+		for _, delta := range []Location{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			n, ok := o.owner[cell.Add(delta)]
+			if ok && n != origin && !seen[n] {
+				seen[n] = true
+				result = append(result, n)
+			}
+		}
+	}
+	sort.Sort(rowMajor(result))
+	return result
+}
+
+// propose picks a random move, or returns nil if the move is not possible.
+func (o *optimizer) propose() *move {
+	origin := o.origins[o.rng.Intn(len(o.origins))]
+	neighbors := o.neighbors(origin)
+
+	switch o.rng.Intn(3) {
+	case 0:
+		// Merge
+		if len(neighbors) == 0 {
+			return nil
+		}
+		other := neighbors[o.rng.Intn(len(neighbors))]
+		cells := append(o.cells(origin), o.cells(other)...)
+		key, min := shapeKey(cells)
+		p, ok := o.shapes[key]
+		if !ok {
+			return nil
+		}
+		_, pieceMin := shapeKey(p.Extent())
+		return &move{o.piecesAt([]Location{origin, other}), map[Location]MosaicPiece{
+			Location{min.Row - pieceMin.Row, min.Col - pieceMin.Col}: p,
+		}}
+	case 1:
+		// Split
+		var smaller []MosaicPiece
+		for _, p := range o.pieces {
+			if len(p.Extent()) < len(o.locs[origin].Extent()) {
+				smaller = append(smaller, p)
+			}
+		}
+		return o.retile([]Location{origin}, smaller)
+	default:
+		// Swap
+		removed := []Location{origin}
+		o.rng.Shuffle(len(neighbors), func(i, j int) {
+			neighbors[i], neighbors[j] = neighbors[j], neighbors[i]
+		})
+		for i := 0; i < len(neighbors) && i < 2; i++ {
+			removed = append(removed, neighbors[i])
+		}
+		return o.retile(removed, o.pieces)
+	}
+}
+
+// cells returns the absolute locations covered by the piece at origin.
+func (o *optimizer) cells(origin Location) []Location {
+	var result []Location
+	for _, rel := range o.locs[origin].Extent() {
+		result = append(result, origin.Add(rel))
+	}
+	return result
+}
+
+// piecesAt returns the current pieces at the origins.
+func (o *optimizer) piecesAt(origins []Location) map[Location]MosaicPiece {
+	result := make(map[Location]MosaicPiece)
+	for _, origin := range origins {
+		result[origin] = o.locs[origin]
+	}
+	return result
+}
+
+// retile removes the pieces at the given origins and covers their cells again with the pieces,
+// tried in a random order, the way GreedySolve would. It returns nil if the cells cannot be
+// covered.
+func (o *optimizer) retile(removed []Location, pieces []MosaicPiece) *move {
+	var cells []Location
+	for _, origin := range removed {
+		cells = append(cells, o.cells(origin)...)
+	}
+	for _, cell := range cells {
+		o.scratch.Set(cell.Row, cell.Col, ToBeFilled)
+	}
+	defer func() {
+		for _, cell := range cells {
+			o.scratch.Set(cell.Row, cell.Col, Empty)
+		}
+	}()
+	shuffled := make([]MosaicPiece, len(pieces))
+	copy(shuffled, pieces)
+	o.rng.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	// Like GreedySolve, work down each column from the left.
+	sort.Sort(colMajor(cells))
+	added := make(map[Location]MosaicPiece)
+	for _, cell := range cells {
+		if o.scratch.Get(cell.Row, cell.Col) != ToBeFilled {
+			continue
+		}
+		for _, p := range shuffled {
+			a := AnchorCell(p.Extent(), UpperLeft)
+			origin := Location{cell.Row - a.Row, cell.Col - a.Col}
+			if o.scratch.PieceFits(p.Extent(), origin) {
+				added[origin] = p
+				for _, rel := range p.Extent() {
+					abs := origin.Add(rel)
+					o.scratch.Set(abs.Row, abs.Col, Filled)
+				}
+				break
+			}
+		}
+		if o.scratch.Get(cell.Row, cell.Col) == ToBeFilled {
+			return nil
+		}
+	}
+	return &move{o.piecesAt(removed), added}
+}
+
+// colMajor sorts locations left to right, top to bottom.
+type colMajor []Location
+
+func (c colMajor) Len() int {
+	return len(c)
+}
+
+func (c colMajor) Less(i, j int) bool {
+	if c[i].Col != c[j].Col {
+		return c[i].Col < c[j].Col
+	}
+	return c[i].Row < c[j].Row
+}
+
+func (c colMajor) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
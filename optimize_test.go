@@ -0,0 +1,203 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOptimizeSolution(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		start  map[Location]MosaicPiece
+		pieces []MosaicPiece
+		opts   OptimizeOptions
+		want   map[Location]MosaicPiece
+	}{
+		{
+			name: "merge two 1x2s into a 1x4",
+			start: map[Location]MosaicPiece{
+				Location{0, 0}: oneByTwo,
+				Location{0, 2}: oneByTwo,
+			},
+			pieces: []MosaicPiece{oneByFour, oneByTwo},
+			opts:   OptimizeOptions{Objective: PieceCountObjective, Iterations: 50},
+			want: map[Location]MosaicPiece{
+				Location{0, 0}: oneByFour,
+			},
+		},
+		{
+			name: "split a 1x4 into cheaper 1x2s",
+			start: map[Location]MosaicPiece{
+				Location{0, 0}: oneByFour,
+			},
+			pieces: []MosaicPiece{oneByFour, oneByTwo},
+			opts:   OptimizeOptions{Objective: CostObjective, Iterations: 50},
+			want: map[Location]MosaicPiece{
+				Location{0, 0}: oneByTwo,
+				Location{0, 2}: oneByTwo,
+			},
+		},
+	} {
+		s := Solution{WithState(1, 4, ToBeFilled), test.start}
+		if got := OptimizeSolution(s, test.pieces, test.opts); !reflect.DeepEqual(got.Pieces, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got.Pieces)
+		}
+	}
+}
+
+func TestOptimizeSolutionKeepsCoverageAndIsReproducible(t *testing.T) {
+	for _, opts := range []OptimizeOptions{
+		{Objective: CostObjective, Orientation: StudsTop, Seed: 1},
+		{Objective: PieceCountObjective, Orientation: StudsTop, Seed: 2, Temperature: 2},
+		{Objective: SeamObjective, Orientation: StudsTop, Seed: 3, Temperature: 1},
+		{Objective: SeamObjective, Orientation: StudsRight, Seed: 4, Temperature: 1},
+	} {
+		pieces := PiecesForOrientation(opts.Orientation, allBricks())
+		g := WithState(9, 12, ToBeFilled)
+		g.Set(4, 4, Empty)
+		start, err := GreedySolve(&g, pieces)
+		if err != nil {
+			t.Fatalf("wanted no error got %v", err)
+		}
+
+		got := OptimizeSolution(start, pieces, opts)
+		checkCovered(t, opts.Objective.String(), got)
+		again := OptimizeSolution(start, pieces, opts)
+		if !reflect.DeepEqual(got.Pieces, again.Pieces) {
+			t.Errorf("for %v the same seed gave different results", opts.Objective)
+		}
+
+		o := newOptimizer(start, pieces, opts)
+		if before, after := o.energy(start.Pieces), o.energy(got.Pieces); after > before {
+			t.Errorf("for %v objective got worse: %v -> %v", opts.Objective, before, after)
+		}
+	}
+}
+
+func TestOptimizerScoreMatchesEnergy(t *testing.T) {
+	for _, opts := range []OptimizeOptions{
+		{Objective: CostObjective, Orientation: StudsTop, Seed: 1},
+		{Objective: PieceCountObjective, Orientation: StudsTop, Seed: 2},
+		{Objective: SeamObjective, Orientation: StudsTop, Seed: 3},
+		{Objective: SeamObjective, Orientation: StudsRight, Seed: 4},
+	} {
+		pieces := PiecesForOrientation(opts.Orientation, allBricks())
+		g := WithState(8, 10, ToBeFilled)
+		start, err := GreedySolve(&g, pieces)
+		if err != nil {
+			t.Fatalf("wanted no error got %v", err)
+		}
+		o := newOptimizer(start, pieces, opts)
+		for i := 0; i < 200; i++ {
+			m := o.propose()
+			if m == nil {
+				continue
+			}
+			o.apply(m)
+			if i%3 == 0 {
+				o.apply(m.reverse())
+			}
+			if got, want := o.score(), o.energy(o.locs); got != want {
+				t.Fatalf("for %v %v after %v moves wanted score %v got %v", opts.Objective, opts.Orientation, i, want, got)
+			}
+		}
+		checkCovered(t, opts.Objective.String(), Solution{start.Original, o.locs})
+	}
+}
+
+func TestOptimizePlan(t *testing.T) {
+	ideal := uniformIdeal{StudsOut, 6, 6, BrightRed}
+	pieces := PiecesForOrientation(StudsOut, allBricks())
+	plan, err := CreateGridMosaic(ideal, GreedySolve, MosaicOptions{})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	got, err := OptimizePlan(plan, pieces, OptimizeOptions{Objective: CostObjective})
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	before, after := plan.Inventory(), got.Inventory()
+	if after.ApproximateCost() > before.ApproximateCost() {
+		t.Errorf("cost went from %d to %d", before.ApproximateCost(), after.ApproximateCost())
+	}
+
+	if _, err := OptimizePlan(brickPlan{}, pieces, OptimizeOptions{}); err == nil {
+		t.Errorf("should not be able to optimize a plan not created by CreateGridMosaic")
+	}
+}
+
+func TestOptimizePlanWithStock(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		stock map[BrickColor]map[Brick]int
+		// built determines whether the plan is created with the stock, or only optimized with it.
+		built   bool
+		backing *Backing
+	}{
+		{
+			name:  "created with the stock",
+			stock: map[BrickColor]map[Brick]int{BrightRed: {OneByFour: 1, OneByOne: 4}},
+			built: true,
+		},
+		{
+			name:  "optimized with the stock",
+			stock: map[BrickColor]map[Brick]int{BrightRed: {OneByTwo: 4, OneByFour: 1}},
+		},
+		{
+			name: "backing laid again from the stock",
+			stock: map[BrickColor]map[Brick]int{
+				BrightRed: {OneByTwo: 4, OneByFour: 1},
+				Black:     {OneByFourPlate: 1, OneByTwoPlate: 2, OneByOnePlate: 2},
+			},
+			built:   true,
+			backing: &Backing{Color: Black},
+		},
+	} {
+		stock := NewStock()
+		for c, counts := range test.stock {
+			for b, n := range counts {
+				stock.Add(c, b, n)
+			}
+		}
+		mosaicOpts := MosaicOptions{Order: ByCost, Backing: test.backing}
+		optimizeOpts := OptimizeOptions{Objective: PieceCountObjective, Iterations: 500, Stock: stock}
+		if test.built {
+			mosaicOpts.Stock = stock
+			optimizeOpts.Stock = nil
+		}
+		plan, err := CreateGridMosaic(uniformIdeal{StudsOut, 1, 8, BrightRed}, GreedySolve, mosaicOpts)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		got, err := OptimizePlan(plan, PiecesForOrientation(StudsOut, allBricks()), optimizeOpts)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		inventory := got.Inventory()
+		for c := range test.stock {
+			used := make(map[Brick]int)
+			for _, b := range inventory.PiecesForColor(c) {
+				used[BaseBrick(b)]++
+			}
+			for b, n := range used {
+				if n > test.stock[c][b] {
+					t.Errorf("for %q used %d of %v %v but only %d in stock", test.name, n, c.name, b.Name(), test.stock[c][b])
+				}
+			}
+		}
+		if len(got.Pieces()) > len(plan.Pieces()) {
+			t.Errorf("for %q pieces went from %d to %d", test.name, len(plan.Pieces()), len(got.Pieces()))
+		}
+	}
+}
+
+func TestObjectiveForName(t *testing.T) {
+	for _, o := range []Objective{CostObjective, PieceCountObjective, SeamObjective} {
+		if got, err := ObjectiveForName(o.String()); err != nil || got != o {
+			t.Errorf("for %v got %v, %v", o, got, err)
+		}
+	}
+	if _, err := ObjectiveForName("beauty"); err == nil {
+		t.Errorf("wanted an error for an unknown objective")
+	}
+}
@@ -0,0 +1,103 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The greedy solvers try the pieces in the order they are given, so the order decides which
+// pieces end up in the mosaic. An ordering policy is a By; CreateGridMosaic sorts every color's
+// pieces with it before solving.
+
+// By is the type of a "less" function that defines the ordering of pieces: p1 should be tried
+// before p2.
+type By func(p1, p2 MosaicPiece) bool
+
+// Sort sorts the pieces in place. Pieces that the ordering considers equal keep their order.
+func (by By) Sort(pieces []MosaicPiece) {
+	sort.Stable(&pieceSorter{pieces, by})
+}
+
+// Then returns an ordering that breaks the ties of by with each of the others in turn.
+func (by By) Then(others ...By) By {
+	policies := append([]By{by}, others...)
+	return func(p1, p2 MosaicPiece) bool {
+		for _, less := range policies {
+			if less(p1, p2) {
+				return true
+			}
+			if less(p2, p1) {
+				return false
+			}
+		}
+		return false
+	}
+}
+
+type pieceSorter struct {
+	pieces []MosaicPiece
+	by     By
+}
+
+func (s *pieceSorter) Len() int {
+	return len(s.pieces)
+}
+
+func (s *pieceSorter) Less(i, j int) bool {
+	return s.by(s.pieces[i], s.pieces[j])
+}
+
+func (s *pieceSorter) Swap(i, j int) {
+	s.pieces[i], s.pieces[j] = s.pieces[j], s.pieces[i]
+}
+
+var (
+	// ByArea tries the pieces covering the most cells first.
+	ByArea By = func(p1, p2 MosaicPiece) bool {
+		return len(p1.Extent()) > len(p2.Extent())
+	}
+
+	// ByCost tries the cheapest pieces first.
+	ByCost By = func(p1, p2 MosaicPiece) bool {
+		return p1.ApproximateCost() < p2.ApproximateCost()
+	}
+
+	// ByCostPerCell tries the pieces that cover a cell most cheaply first. Ties are broken in favor
+	// of the piece covering more cells, since it takes fewer pieces to fill the same space.
+	ByCostPerCell By = func(p1, p2 MosaicPiece) bool {
+		c1, c2 := costPerCell(p1), costPerCell(p2)
+		if c1 != c2 {
+			return c1 < c2
+		}
+		return len(p1.Extent()) > len(p2.Extent())
+	}
+
+	// ByLength tries the longest pieces first, whichever way they are turned.
+	ByLength By = func(p1, p2 MosaicPiece) bool {
+		return p1.Length() > p2.Length()
+	}
+
+	orderings = map[string]By{
+		"area":        ByArea,
+		"cost":        ByCost,
+		"costpercell": ByCostPerCell,
+		"length":      ByLength,
+	}
+)
+
+// OrderingForName returns the ordering policy with the given name: one of area, cost,
+// costpercell or length. A comma separated list of names gives a composite ordering, with ties
+// broken by each policy in turn; e.g. "length,cost" tries the longest pieces first, the cheapest of
+// them before the rest.
+func OrderingForName(name string) (By, error) {
+	var policies []By
+	for _, n := range strings.Split(name, ",") {
+		by, ok := orderings[strings.TrimSpace(n)]
+		if !ok {
+			return nil, fmt.Errorf("unknown ordering %q; wanted a comma separated list of area, cost, costpercell or length", n)
+		}
+		policies = append(policies, by)
+	}
+	return policies[0].Then(policies[1:]...), nil
+}
@@ -0,0 +1,60 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderings(t *testing.T) {
+	// oneByFour costs 7 for 4 cells, oneByTwo 2 for 2, oneByOne 4 for 1 and twoByTwo 4 for 4.
+	pieces := []MosaicPiece{oneByOne, oneByTwo, oneByFour, twoByTwo}
+	for _, test := range []struct {
+		name string
+		want []MosaicPiece
+	}{
+		{"area", []MosaicPiece{oneByFour, twoByTwo, oneByTwo, oneByOne}},
+		{"cost", []MosaicPiece{oneByTwo, oneByOne, twoByTwo, oneByFour}},
+		{"costpercell", []MosaicPiece{twoByTwo, oneByTwo, oneByFour, oneByOne}},
+		{"length", []MosaicPiece{oneByFour, oneByTwo, twoByTwo, oneByOne}},
+		{"length,cost", []MosaicPiece{oneByFour, oneByTwo, twoByTwo, oneByOne}},
+		{"area, cost", []MosaicPiece{twoByTwo, oneByFour, oneByTwo, oneByOne}},
+	} {
+		by, err := OrderingForName(test.name)
+		if err != nil {
+			t.Errorf("for %q wanted no error got %v", test.name, err)
+			continue
+		}
+		got := make([]MosaicPiece, len(pieces))
+		copy(got, pieces)
+		by.Sort(got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got)
+		}
+	}
+
+	for _, name := range []string{"", "beauty", "area,"} {
+		if _, err := OrderingForName(name); err == nil {
+			t.Errorf("for %q wanted an error", name)
+		}
+	}
+}
+
+func TestCreateGridMosaicWithOrder(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		order By
+		want  int
+	}{
+		{"catalog order", nil, 1},
+		{"cheapest first", ByCost, 2},
+	} {
+		opts := MosaicOptions{Bricks: []Brick{OneByFour, OneByTwo, OneByOne}, Order: test.order}
+		plan, err := CreateGridMosaic(uniformIdeal{StudsTop, 3, 4, BrightRed}, GreedySolve, opts)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		if got := len(plan.Pieces()); got != test.want {
+			t.Errorf("for %q wanted %d pieces got %d", test.name, test.want, got)
+		}
+	}
+}
@@ -4,6 +4,8 @@ package BrickMosaic
 
 import (
 	"image/color"
+	"strings"
+	"sync"
 )
 
 // BrickColor represents the color of a LEGO brick. It implements the color.Color interface via delegation.
@@ -290,8 +292,99 @@ var (
 		Black,
 	})
 	nameMap map[string]BrickColor = buildNameMap()
+
+	// brickLinkNames maps the names BrickLink gives colors, e.g. in its price guides, to the colors
+	// of the FullPalette. Only colors whose BrickLink name is certain are listed. Some names mean a
+	// different color than they do in this package: BrickLink's "Dark Green" is EarthGreen, and its
+	// "Brown" is EarthOrange.
+	brickLinkNames = map[string]BrickColor{
+		"White":                  White,
+		"Light Gray":             Grey,
+		"Light Yellow":           LightYellow,
+		"Tan":                    BrickYellow,
+		"Light Green":            LightGreen,
+		"Pink":                   LightReddishViolet,
+		"Earth Orange":           LightOrangeBrown,
+		"Nougat":                 Nougat,
+		"Red":                    BrightRed,
+		"Blue":                   BrightBlue,
+		"Yellow":                 BrightYellow,
+		"Brown":                  EarthOrange,
+		"Black":                  Black,
+		"Dark Gray":              DarkGrey,
+		"Green":                  DarkGreen,
+		"Medium Green":           MediumGreen,
+		"Bright Green":           BrightGreen,
+		"Dark Orange":            DarkOrange,
+		"Light Blue":             LightBlue,
+		"Medium Blue":            MediumBlue,
+		"Very Light Gray":        LightGrey,
+		"Purple":                 BrightViolet,
+		"Orange":                 BrightOrange,
+		"Dark Turquoise":         BrightBluishGreen,
+		"Violet":                 BrightBluishViolet,
+		"Medium Violet":          MediumBluishViolet,
+		"Medium Lime":            MedYellowishGreen,
+		"Light Aqua":             LightBluishGreen,
+		"Lime":                   BrYellowishGreen,
+		"Light Lime":             LigYellowishGreen,
+		"Magenta":                BrightReddishViolet,
+		"Sand Blue":              SandBlue,
+		"Dark Tan":               SandYellow,
+		"Dark Blue":              EarthBlue,
+		"Dark Green":             EarthGreen,
+		"Pearl Dark Gray":        DarkGreyMetallic,
+		"Pearl Light Gray":       LightGreyMetallic,
+		"Sand Green":             Sand,
+		"Sand Red":               SandRed,
+		"Dark Red":               DarkRed,
+		"Bright Light Orange":    FlameYellowishOrange,
+		"Reddish Brown":          ReddishBrown,
+		"Light Bluish Gray":      MediumStoneGrey,
+		"Dark Bluish Gray":       DarkStoneGrey,
+		"Very Light Bluish Gray": LightStoneGrey,
+		"Bright Light Blue":      LightRoyalBlue,
+		"Rust":                   Rust,
+		"Dark Pink":              BrightPurple,
+		"Bright Pink":            LightPurple,
+		"Bright Light Yellow":    CoolYellow,
+		"Dark Purple":            MediumLilac,
+		"Trans-Clear":            Transparent,
+		"Trans-Red":              TrRed,
+		"Trans-Light Blue":       TrLgBlue,
+		"Trans-Dark Blue":        TrBlue,
+		"Trans-Yellow":           TrYellow,
+		"Trans-Neon Orange":      TrFluReddishOrange,
+		"Trans-Green":            TrGreen,
+		"Trans-Neon Green":       TrFluGreen,
+		"Trans-Black":            TrBrown,
+		"Trans-Dark Pink":        TrMediReddishViolet,
+		"Trans-Purple":           TrBrightBluishViolet,
+		"Trans-Medium Blue":      TrFluBlue,
+		"Trans-Neon Yellow":      TrFluYellow,
+	}
 )
 
+// BrickLinkColor returns the color of the FullPalette that BrickLink calls name, e.g. "Light
+// Bluish Gray" is MediumStoneGrey, or nil.
+func BrickLinkColor(name string) *BrickColor {
+	if c, ok := brickLinkNames[strings.TrimSpace(name)]; ok {
+		return &c
+	}
+	return nil
+}
+
+// builtIn determines whether the color is one of the FullPalette, rather than one loaded from a
+// color file.
+func builtIn(c BrickColor) bool {
+	for _, p := range FullPalette {
+		if p == color.Color(c) {
+			return true
+		}
+	}
+	return false
+}
+
 func buildNameMap() map[string]BrickColor {
 	nameMap := make(map[string]BrickColor)
 	for _, color := range FullPalette {
@@ -301,10 +394,28 @@ func buildNameMap() map[string]BrickColor {
 	return nameMap
 }
 
-// ColorForName returns the BrickColor whose name matches n, or nil.
+// nameLock guards nameMap, which RegisterColors adds to.
+var nameLock sync.RWMutex
+
+// RegisterColors makes the colors known to ColorForName, in place of any colors of the same name.
+func RegisterColors(colors []BrickColor) {
+	nameLock.Lock()
+	defer nameLock.Unlock()
+	for _, c := range colors {
+		nameMap[c.name] = c
+	}
+}
+
+// ColorForName returns the BrickColor whose name matches n, or nil. Names written as they are in
+// a color file also match, so "Bright Red" is BrightRed.
 func ColorForName(n string) *BrickColor {
+	nameLock.RLock()
+	defer nameLock.RUnlock()
 	if c, ok := nameMap[n]; ok {
 		return &c
 	}
+	if c, ok := nameMap[colorName(n)]; ok {
+		return &c
+	}
 	return nil
 }
@@ -0,0 +1,114 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parts can be chosen from a list of bricks with a short spec: a comma separated list of part ids,
+// or the groups "all", "bricks" and "plates". A leading "-" removes parts instead of adding them,
+// and a spec that starts by removing parts starts from all of them. For example:
+//
+//	plates          only plates
+//	-3005           everything but 1x1 bricks
+//	bricks,-3005    bricks, but not 1x1s
+//	3024,3023,3710  only 1x1, 1x2 and 1x4 plates
+
+// SelectParts returns the bricks chosen by the spec, in the order they are listed in bricks.
+func SelectParts(bricks []Brick, spec string) ([]Brick, error) {
+	chosen := make(map[Brick]bool)
+	byId := make(map[string]Brick)
+	for _, b := range bricks {
+		byId[b.Id()] = b
+	}
+	for i, token := range strings.Split(spec, ",") {
+		token = strings.TrimSpace(token)
+		remove := strings.HasPrefix(token, "-")
+		if remove {
+			token = strings.TrimSpace(token[1:])
+			if i == 0 {
+				for _, b := range bricks {
+					chosen[b] = true
+				}
+			}
+		}
+		var matches []Brick
+		switch token {
+		case "all":
+			matches = bricks
+		case "bricks", "plates":
+			for _, b := range bricks {
+				if isPlate(b) == (token == "plates") {
+					matches = append(matches, b)
+				}
+			}
+		default:
+			b, ok := byId[token]
+			if !ok {
+				return nil, fmt.Errorf("unknown part %q in %q", token, spec)
+			}
+			matches = []Brick{b}
+		}
+		for _, b := range matches {
+			chosen[b] = !remove
+		}
+	}
+
+	var result []Brick
+	for _, b := range bricks {
+		if chosen[b] {
+			result = append(result, b)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no parts left after %q", spec)
+	}
+	return result, nil
+}
+
+// ParsePartsForColor reads the parts allowed in particular colors from a semicolon separated list
+// of color:spec pairs, where each spec is as for SelectParts, e.g.
+//
+//	Transparent:3024,3023;Black:-3005
+func ParsePartsForColor(bricks []Brick, s string) (map[BrickColor][]Brick, error) {
+	result := make(map[BrickColor][]Brick)
+	for _, pair := range strings.Split(s, ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("wanted color:parts got %q", pair)
+		}
+		c := ColorForName(strings.TrimSpace(parts[0]))
+		if c == nil {
+			return nil, fmt.Errorf("unknown color %q", parts[0])
+		}
+		selected, err := SelectParts(bricks, parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("for %v: %v", c.name, err)
+		}
+		result[*c] = selected
+	}
+	return result, nil
+}
+
+// isPlate determines whether the brick is a plate rather than a full height brick.
+func isPlate(b Brick) bool {
+	return b.Height() < OneByOne.Height()
+}
+
+// onlyParts returns the pieces that are made from one of the parts.
+func onlyParts(pieces []MosaicPiece, parts []Brick) []MosaicPiece {
+	allowed := make(map[Brick]bool)
+	for _, b := range parts {
+		allowed[BaseBrick(b)] = true
+	}
+	var result []MosaicPiece
+	for _, p := range pieces {
+		if allowed[BaseBrick(p)] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
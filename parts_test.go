@@ -0,0 +1,77 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectParts(t *testing.T) {
+	bricks := []Brick{TwoByFour, OneByOne, OneByFourPlate, OneByOnePlate}
+	for _, test := range []struct {
+		spec string
+		want []Brick
+	}{
+		{"all", bricks},
+		{"plates", []Brick{OneByFourPlate, OneByOnePlate}},
+		{"-3005", []Brick{TwoByFour, OneByFourPlate, OneByOnePlate}},
+		{"bricks, -3005", []Brick{TwoByFour}},
+		{"3024,3001", []Brick{TwoByFour, OneByOnePlate}},
+	} {
+		got, err := SelectParts(bricks, test.spec)
+		if err != nil {
+			t.Errorf("for %q wanted no error got %v", test.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.spec, test.want, got)
+		}
+	}
+
+	for _, spec := range []string{"3008", "all,-all", "stickers"} {
+		if _, err := SelectParts(bricks, spec); err == nil {
+			t.Errorf("for %q wanted an error", spec)
+		}
+	}
+}
+
+func TestParsePartsForColor(t *testing.T) {
+	got, err := ParsePartsForColor(allBricks(), "Transparent:3024,3023; Black:plates")
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	want := map[BrickColor][]Brick{
+		Transparent: {OneByTwoPlate, OneByOnePlate},
+		Black:       Plates,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v got %v", want, got)
+	}
+
+	for _, s := range []string{"Transparent", "Plaid:3024", "Black:3008x"} {
+		if _, err := ParsePartsForColor(allBricks(), s); err == nil {
+			t.Errorf("for %q wanted an error", s)
+		}
+	}
+}
+
+func TestCreateGridMosaicWithPartsForColor(t *testing.T) {
+	opts := MosaicOptions{
+		Bricks:        []Brick{OneByFour, OneByTwo, OneByOne},
+		PartsForColor: map[BrickColor][]Brick{Black: {OneByOne}},
+	}
+	for _, test := range []struct {
+		c    BrickColor
+		want int
+	}{
+		{BrightRed, 1},
+		{Black, 4},
+	} {
+		plan, err := CreateGridMosaic(uniformIdeal{StudsTop, 3, 4, test.c}, GreedySolve, opts)
+		if err != nil {
+			t.Fatalf("for %v wanted no error got %v", test.c.name, err)
+		}
+		if got := len(plan.Pieces()); got != test.want {
+			t.Errorf("for %v wanted %d pieces got %d", test.c.name, test.want, got)
+		}
+	}
+}
@@ -62,10 +62,6 @@ type Piece interface {
 	Extent() []Location
 }
 
-// TODO(ndunn): finish this, sort by price, size (number of grid lcoations it takes up)
-// By is the type of a "less" function that defines the ordering of its Piece pieces
-type By func(p1, p2 *Piece) bool
-
 // RectPiece represents a rectangular piece.
 type RectPiece struct {
 	NumRows int
@@ -81,3 +77,40 @@ func (r RectPiece) Extent() []Location {
 	}
 	return locs
 }
+
+// ShapedPiece is a piece that covers only some of the cells of its bounding rectangle.
+type ShapedPiece struct {
+	Rect RectPiece
+	// Cells marks the covered cells, one bit per cell in row major order.
+	Cells uint64
+}
+
+func (s ShapedPiece) Extent() []Location {
+	var locs []Location
+	for _, loc := range s.Rect.Extent() {
+		if s.Cells&(1<<uint(loc.Row*s.Rect.NumCols+loc.Col)) != 0 {
+			locs = append(locs, loc)
+		}
+	}
+	return locs
+}
+
+// mask returns the piece covering only the marked cells of r, or r itself if mask is 0.
+func (r RectPiece) mask(mask uint64) Piece {
+	if mask == 0 {
+		return r
+	}
+	return ShapedPiece{r, mask}
+}
+
+// shapeOf marks the given cells of r, as for mask. If they cover all of r, it returns 0.
+func (r RectPiece) shapeOf(cells []Location) uint64 {
+	if len(cells) == r.NumRows*r.NumCols {
+		return 0
+	}
+	var mask uint64
+	for _, loc := range cells {
+		mask |= 1 << uint(loc.Row*r.NumCols+loc.Col)
+	}
+	return mask
+}
@@ -47,14 +47,22 @@ type BrickImage struct {
 	// through the image.
 	errorScalingFactor float32
 
+	// distance picks the nearest palette color, and the color space the dithering error is spread in.
+	distance ColorDistance
+	// space and reducer are how the pixels of each cell are reduced to one color.
+	space   ColorSpace
+	reducer Reducer
+	// masked are the cells left out of the mosaic.
+	masked map[Location]bool
+
 	// Frames are snapshots of the process of creating the final image, for debuggin
 	// purposes
 	Frames []*image.Paletted
 }
 
 // AverageColor determines the 'average' color of the subimage whose coordinates are contained in the
-// given bounds. The average is an arithmetic average in RGB color space.
-// TODO(ndunn): try different color spaces.
+// given bounds. The average is an arithmetic average in RGB color space. CellColor reduces colors
+// in other color spaces, and in other ways.
 func AverageColor(si image.Image, bounds image.Rectangle) color.Color {
 	R, G, B, A := uint64(0), uint64(0), uint64(0), uint64(0)
 	numPixels := uint64(0)
@@ -105,12 +113,12 @@ func lerp(v1, v2, amt float64) float64 {
 	return ((v2 - v1) * amt) + v1
 }
 
-func (si *BrickImage) rowToY(row int) int {
-	return int(doMap(float64(row), 0.0, float64(si.rows), float64(si.img.Bounds().Min.Y), float64(si.img.Bounds().Max.Y)))
+func (si *BrickImage) rowToY(row int) float64 {
+	return doMap(float64(row), 0.0, float64(si.rows), float64(si.img.Bounds().Min.Y), float64(si.img.Bounds().Max.Y))
 }
 
-func (si *BrickImage) colToX(col int) int {
-	return int(doMap(float64(col), 0.0, float64(si.cols), float64(si.img.Bounds().Min.X), float64(si.img.Bounds().Max.X)))
+func (si *BrickImage) colToX(col int) float64 {
+	return doMap(float64(col), 0.0, float64(si.cols), float64(si.img.Bounds().Min.X), float64(si.img.Bounds().Max.X))
 }
 
 func (si *BrickImage) ColorModel() color.Model {
@@ -122,6 +130,17 @@ func (si *BrickImage) Bounds() image.Rectangle {
 	return image.Rectangle{image.Pt(0, 0), image.Pt(scaleFactor*si.cols, scaleFactor*si.rows)}
 }
 
+// Masked determines whether the row / col combination is left out of the mosaic.
+func (si *BrickImage) Masked(row, col int) bool {
+	return si.masked[Location{row, col}]
+}
+
+// cellBounds returns the part of the image covered by the row / col combination.
+func (si *BrickImage) cellBounds(row, col int) (x0, y0, x1, y1 float64) {
+	// Convert rows/columns into x/y coordinates in the image. They need not be whole pixels.
+	return si.colToX(col), si.rowToY(row), si.colToX(col + 1), si.rowToY(row + 1)
+}
+
 // Color returns the best palette.BrickColor for the given row/column
 // in the image based on the palette this image was instantiated with.
 func (si *BrickImage) Color(row, col int) BrickColor {
@@ -130,7 +149,7 @@ func (si *BrickImage) Color(row, col int) BrickColor {
 		return c
 	}
 	avgColor := si.IdealColor(row, col)
-	bestMatch := si.palette.Convert(avgColor).(BrickColor)
+	bestMatch := si.distance.Nearest(si.palette, avgColor).(BrickColor)
 	si.avgColors[loc] = bestMatch
 	return bestMatch
 }
@@ -144,16 +163,33 @@ func (si *BrickImage) IdealColor(row, col int) color.Color {
 		return c
 	}
 
-	// Convert rows/columns into x/y coordinates in the image
-	y1 := si.rowToY(row)
-	y2 := si.rowToY(row + 1)
+	return si.cellColor(row, col)
+}
 
-	x1 := si.colToX(col)
-	x2 := si.colToX(col + 1)
+// cellColor reduces the part of the image covered by the row / col combination to one color.
+func (si *BrickImage) cellColor(row, col int) color.Color {
+	x0, y0, x1, y1 := si.cellBounds(row, col)
+	return CellColor(si.img, x0, y0, x1, y1, si.space, si.reducer)
+}
 
-	bounds := image.Rect(x1, y1, x2, y2)
-	avgColor := AverageColor(si.img, bounds)
-	return avgColor
+// MeanColorDistance reports how faithful the posterized image is: the average distance between
+// the color of each cell of the original image and the brick color chosen for it, measured with
+// the same ColorDistance the colors were chosen with. Masked cells are left out.
+func (si *BrickImage) MeanColorDistance() float64 {
+	total, n := 0.0, 0
+	for row := 0; row < si.rows; row++ {
+		for col := 0; col < si.cols; col++ {
+			if si.Masked(row, col) {
+				continue
+			}
+			total += si.distance.Distance(si.cellColor(row, col), si.Color(row, col))
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return total / float64(n)
 }
 
 // Paletted renders the current state of the image as a Paletted image. Useful for debugging
@@ -247,8 +283,34 @@ func EucPosterize(img image.Image, p color.Palette, rows int, cols int, o ViewOr
 	return NewBrickImage(img, rows, cols, p, o, 0.0)
 }
 
+// PosterizeOptions configures how PosterizeWith turns an image into an Ideal.
+type PosterizeOptions struct {
+	// Dither scales the quantization error that is spread to the neighboring cells: 0 for no
+	// dithering at all, 1 for the standard amount.
+	Dither float32
+	// Distance picks the nearest palette color for each cell. With the CIE metrics, the dithering
+	// error is also spread in CIELAB rather than RGB.
+	Distance ColorDistance
+	// Space is the color space the pixels of each cell are reduced to one color in.
+	Space ColorSpace
+	// Reducer is how the pixels of each cell are reduced to one color.
+	Reducer Reducer
+	// Mask picks the cells that are left out of the mosaic.
+	Mask Mask
+}
+
+// PosterizeWith returns an Ideal representation of the image configured by the options.
+func PosterizeWith(img image.Image, p color.Palette, rows int, cols int, o ViewOrientation, opts PosterizeOptions) *BrickImage {
+	return newBrickImage(img, rows, cols, p, o, opts)
+}
+
 // NewBrickImage returns a BrickImage based on the given inputs.
 func NewBrickImage(img image.Image, rows, cols int, palette color.Palette, o ViewOrientation, errorScalingFactor float32) *BrickImage {
+	return newBrickImage(img, rows, cols, palette, o, PosterizeOptions{Dither: errorScalingFactor})
+}
+
+func newBrickImage(img image.Image, rows, cols int, palette color.Palette, o ViewOrientation, opts PosterizeOptions) *BrickImage {
+	errorScalingFactor := opts.Dither
 	brickImage := &BrickImage{
 		img:                img,
 		palette:            palette,
@@ -258,6 +320,10 @@ func NewBrickImage(img image.Image, rows, cols int, palette color.Palette, o Vie
 		avgColors:          make(map[Location]BrickColor),
 		orientation:        o,
 		errorScalingFactor: errorScalingFactor,
+		distance:           opts.Distance,
+		space:              opts.Space,
+		reducer:            opts.Reducer,
+		masked:             make(map[Location]bool),
 		Frames:             nil,
 	}
 
@@ -266,6 +332,10 @@ func NewBrickImage(img image.Image, rows, cols int, palette color.Palette, o Vie
 		for col := 0; col < cols; col++ {
 			oldPixel := brickImage.IdealColor(row, col)
 			brickImage.colors[Location{row, col}] = oldPixel
+			x0, y0, x1, y1 := brickImage.cellBounds(row, col)
+			if opts.Mask.covers(img, x0, y0, x1, y1, opts.Distance) {
+				brickImage.masked[Location{row, col}] = true
+			}
 		}
 	}
 	//brickImage.Frames = append(brickImage.Frames, brickImage.Paletted())
@@ -273,28 +343,44 @@ func NewBrickImage(img image.Image, rows, cols int, palette color.Palette, o Vie
 	// Initialize the color map
 	for row := 0; row < rows; row++ {
 		for col := 0; col < cols; col++ {
+			// The background of the image is not spread into the mosaic.
+			if brickImage.Masked(row, col) {
+				continue
+			}
 			oldPixel := brickImage.IdealColor(row, col)
 			bestMatch := brickImage.Color(row, col)
 			err := Error(oldPixel, bestMatch)
+			var lErr labError
+			if opts.Distance != RGBDistance {
+				lErr = newLabError(oldPixel, bestMatch)
+			}
+			// spread adds the given share of the error to the cell at loc.
+			spread := func(loc Location, share float32) {
+				if opts.Distance == RGBDistance {
+					brickImage.colors[loc] = AddError(brickImage.colors[loc], err.Scale(errorScalingFactor*share))
+				} else if errorScalingFactor != 0 {
+					brickImage.colors[loc] = lErr.addTo(brickImage.colors[loc], errorScalingFactor*share)
+				}
+			}
 
 			if col != cols-1 {
 				// To the right
-				brickImage.colors[Location{row, col + 1}] = AddError(brickImage.colors[Location{row, col + 1}], err.Scale(errorScalingFactor*7.0/16.0))
+				spread(Location{row, col + 1}, 7.0/16.0)
 			}
 
 			if col != 0 && row != rows-1 {
 				// To Left, below
-				brickImage.colors[Location{row + 1, col - 1}] = AddError(brickImage.colors[Location{row + 1, col - 1}], err.Scale(errorScalingFactor*3.0/16.0))
+				spread(Location{row + 1, col - 1}, 3.0/16.0)
 			}
 
 			if row != rows-1 {
 				// Center, below
-				brickImage.colors[Location{row + 1, col}] = AddError(brickImage.colors[Location{row + 1, col}], err.Scale(errorScalingFactor*5.0/16.0))
+				spread(Location{row + 1, col}, 5.0/16.0)
 			}
 
 			if row != rows-1 && col != cols-1 {
 				// To right, below
-				brickImage.colors[Location{row + 1, col + 1}] = AddError(brickImage.colors[Location{row + 1, col + 1}], err.Scale(errorScalingFactor*1.0/16.0))
+				spread(Location{row + 1, col + 1}, 1.0/16.0)
 			}
 			//brickImage.Frames = append(brickImage.Frames, brickImage.Paletted())
 		}
@@ -0,0 +1,197 @@
+package BrickMosaic
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PriceTable holds what parts cost in particular colors. Parts are looked up by their Id. A part
+// that is not in the table costs its ApproximateCost, whatever the color. A nil *PriceTable is
+// valid and holds no prices.
+type PriceTable struct {
+	prices map[string]map[BrickColor]int
+	// skipped are the names of the colors ParsePriceGuide could not find, and clashes the colors it
+	// found under two names, in the order they came.
+	skipped, clashes []string
+}
+
+// NewPriceTable returns an empty price table.
+func NewPriceTable() *PriceTable {
+	return &PriceTable{prices: make(map[string]map[BrickColor]int)}
+}
+
+// Skipped returns the names of the colors in the price guide that no color is known by, whose
+// parts therefore cost their ApproximateCost.
+func (t *PriceTable) Skipped() []string {
+	if t == nil {
+		return nil
+	}
+	return t.skipped
+}
+
+// Clashes describes the colors that the price guide named in two ways, e.g. "Bright Red" and
+// "Red", and which name's prices were used.
+func (t *PriceTable) Clashes() []string {
+	if t == nil {
+		return nil
+	}
+	return t.clashes
+}
+
+// Set sets the price in cents of the part with the given id in the given color.
+func (t *PriceTable) Set(id string, c BrickColor, cents int) {
+	if t.prices[id] == nil {
+		t.prices[id] = make(map[BrickColor]int)
+	}
+	t.prices[id][c] = cents
+}
+
+// Price returns the price in cents of the part in the given color.
+func (t *PriceTable) Price(b Brick, c BrickColor) int {
+	if t != nil {
+		if cents, ok := t.prices[b.Id()][c]; ok {
+			return cents
+		}
+	}
+	return b.ApproximateCost()
+}
+
+// PiecesForColor returns the pieces with their ApproximateCost replaced by their price in the given
+// color, so that the cost-aware solvers pick the parts that are cheapest in that color.
+func (t *PriceTable) PiecesForColor(c BrickColor, pieces []MosaicPiece) []MosaicPiece {
+	if t == nil {
+		return pieces
+	}
+	result := make([]MosaicPiece, len(pieces))
+	for i, p := range pieces {
+		result[i] = pricedPiece{p, t.Price(p, c)}
+	}
+	return result
+}
+
+// pricedPiece is a piece whose price depends on its color.
+type pricedPiece struct {
+	MosaicPiece
+	cost int
+}
+
+func (p pricedPiece) ApproximateCost() int {
+	return p.cost
+}
+
+// ParsePriceGuide reads a price table from CSV in the style of a BrickLink price guide export.
+// The first line names the columns; the "Item No", "Color" and "Avg Price" columns are used and
+// any others are ignored. Prices are in dollars, e.g. "US $0.05". Colors are looked up by their
+// BrickLink name with BrickLinkColor, so "Light Bluish Gray" is MediumStoneGrey, and failing that
+// with ColorForName, so "Bright Red" is BrightRed and colors loaded from a BrickLink color file are
+// found too. Rows for colors that are known by neither are skipped, and listed by Skipped. If two
+// names come to the same color, the BrickLink name's price is used whichever row comes first, and
+// the clash is listed by Clashes.
+func ParsePriceGuide(r io.Reader) (*PriceTable, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("price guide is empty")
+	}
+	column := make(map[string]int)
+	for i, name := range records[0] {
+		column[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"item no", "color", "avg price"} {
+		if _, ok := column[name]; !ok {
+			return nil, fmt.Errorf("price guide is missing the %q column", name)
+		}
+	}
+
+	table := NewPriceTable()
+	skipped := make(map[string]bool)
+	// sources records the color name each price was set from, and whether it was a BrickLink name.
+	type source struct {
+		name      string
+		brickLink bool
+	}
+	sources := make(map[string]map[BrickColor]source)
+	clashes := make(map[string]bool)
+	for i, record := range records[1:] {
+		if len(record) != len(records[0]) {
+			return nil, fmt.Errorf("line %d: wanted %d fields got %d", i+2, len(records[0]), len(record))
+		}
+		name := strings.TrimSpace(record[column["color"]])
+		colors, brickLink := priceGuideColors(name)
+		if len(colors) == 0 {
+			if !skipped[name] {
+				skipped[name] = true
+				table.skipped = append(table.skipped, name)
+			}
+			continue
+		}
+		cents, err := parseDollars(record[column["avg price"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+2, err)
+		}
+		id := strings.TrimSpace(record[column["item no"]])
+		if sources[id] == nil {
+			sources[id] = make(map[BrickColor]source)
+		}
+		for j, c := range colors {
+			current := source{name, brickLink && j == 0}
+			if prev, ok := sources[id][c]; ok && prev.name != name {
+				used := current
+				if prev.brickLink && !current.brickLink {
+					used = prev
+				}
+				clash := fmt.Sprintf("%v and %v are both %v; using %v", prev.name, name, c.name, used.name)
+				if !clashes[clash] {
+					clashes[clash] = true
+					table.clashes = append(table.clashes, clash)
+				}
+				if used == prev {
+					continue
+				}
+			}
+			sources[id][c] = current
+			table.Set(id, c, cents)
+		}
+	}
+	return table, nil
+}
+
+// priceGuideColors returns the colors a price guide's color name stands for, and whether the first
+// of them is the color of that BrickLink name. The BrickLink name wins over a built-in color of the
+// same name, e.g. "Dark Green" is EarthGreen rather than DarkGreen, but a color loaded from a color
+// file under that name is priced too.
+func priceGuideColors(name string) ([]BrickColor, bool) {
+	var colors []BrickColor
+	if c := BrickLinkColor(name); c != nil {
+		colors = append(colors, *c)
+	}
+	brickLink := len(colors) > 0
+	if c := ColorForName(name); c != nil && (len(colors) == 0 || (!builtIn(*c) && *c != colors[0])) {
+		colors = append(colors, *c)
+	}
+	return colors, brickLink
+}
+
+// parseDollars converts a price such as "US $1,234.56" to cents.
+func parseDollars(s string) (int, error) {
+	trimmed := strings.TrimSpace(s)
+	trimmed = strings.TrimPrefix(trimmed, "US")
+	trimmed = strings.TrimSpace(trimmed)
+	trimmed = strings.TrimPrefix(trimmed, "$")
+	trimmed = strings.Replace(trimmed, ",", "", -1)
+	dollars, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || dollars < 0 {
+		return 0, fmt.Errorf("invalid price %q", s)
+	}
+	return int(math.Round(dollars * 100)), nil
+}
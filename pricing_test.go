@@ -0,0 +1,122 @@
+package BrickMosaic
+
+import (
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePriceGuide(t *testing.T) {
+	input := "Item No,Color,Min Price,Avg Price,Max Price\n" +
+		"3008,Black,US $0.05,US $0.08,US $0.20\n" +
+		"3008,Bright Red,$0.30,$0.45,$1.00\n" +
+		"3008,Speckle Black-Silver,$9.00,$9.99,$12.00\n" +
+		"3008,Red,$0.10,$0.12,$0.50\n" +
+		"3008,Light Bluish Gray,$0.02,$0.04,$0.09\n" +
+		"3008,Tan,$0.10,$0.11,$0.15\n" +
+		"3008,Dark Green,$0.20,$0.25,$0.30\n" +
+		"3009,Speckle Black-Silver,$9.00,$9.99,$12.00\n"
+	table, err := ParsePriceGuide(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	for _, test := range []struct {
+		c    BrickColor
+		b    Brick
+		want int
+	}{
+		{Black, OneByEight, 8},
+		// Both name BrightRed; the BrickLink name's price wins.
+		{BrightRed, OneByEight, 12},
+		{MediumStoneGrey, OneByEight, 4},
+		{BrickYellow, OneByEight, 11},
+		{EarthGreen, OneByEight, 25},
+		// BrickLink's Dark Green is not DarkGreen, so the default price.
+		{DarkGreen, OneByEight, 30},
+		// Not in the table, so the default price.
+		{White, OneByEight, 30},
+		{Black, OneByFour, 7},
+	} {
+		if got := table.Price(test.b, test.c); got != test.want {
+			t.Errorf("for %v %v wanted %d got %d", test.c.name, test.b.Name(), test.want, got)
+		}
+	}
+	if got, want := table.Skipped(), []string{"Speckle Black-Silver"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted skipped %v got %v", want, got)
+	}
+
+	for _, input := range []string{
+		"",
+		"Item No,Color\n3008,Black\n",
+		"Item No,Color,Avg Price\n3008,Black,cheap\n",
+		"Item No,Color,Avg Price\n3008,Black\n",
+	} {
+		if _, err := ParsePriceGuide(strings.NewReader(input)); err == nil {
+			t.Errorf("for %q wanted an error", input)
+		}
+	}
+}
+
+func TestParsePriceGuideClashes(t *testing.T) {
+	for _, input := range []string{
+		"Item No,Color,Avg Price\n3008,Bright Red,$0.45\n3008,Red,$0.12\n3009,Bright Red,$0.50\n3009,Red,$0.20\n",
+		"Item No,Color,Avg Price\n3008,Red,$0.12\n3008,Bright Red,$0.45\n3009,Red,$0.20\n3009,Bright Red,$0.50\n",
+	} {
+		table, err := ParsePriceGuide(strings.NewReader(input))
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", input, err)
+		}
+		if got := table.Price(OneByEight, BrightRed); got != 12 {
+			t.Errorf("for %q wanted the BrickLink name's price 12 got %d", input, got)
+		}
+		if got := len(table.Clashes()); got != 1 {
+			t.Errorf("for %q wanted one clash got %v", input, table.Clashes())
+		}
+		if got := table.Clashes(); len(got) > 0 && !strings.HasSuffix(got[0], "are both BrightRed; using Red") {
+			t.Errorf("for %q wanted the clash to name BrightRed and Red got %q", input, got[0])
+		}
+	}
+}
+
+func TestCreateGridMosaicWithPrices(t *testing.T) {
+	prices := NewPriceTable()
+	// A 1x4 is cheaper than two 1x2s in red, but not in black.
+	prices.Set(OneByFour.Id(), BrightRed, 1)
+	for _, test := range []struct {
+		c      BrickColor
+		pieces int
+		cost   int
+	}{
+		{BrightRed, 1, 1},
+		{Black, 2, 4},
+	} {
+		plan, err := CreateGridMosaic(uniformIdeal{StudsTop, 3, 4, test.c}, GreedyMinCostSolve, MosaicOptions{Prices: prices})
+		if err != nil {
+			t.Fatalf("wanted no error got %v", err)
+		}
+		if got := len(plan.Pieces()); got != test.pieces {
+			t.Errorf("for %v wanted %d pieces got %d", test.c.name, test.pieces, got)
+		}
+		inventory := plan.Inventory()
+		if got := inventory.ApproximateCost(); got != test.cost {
+			t.Errorf("for %v wanted cost %d got %d", test.c.name, test.cost, got)
+		}
+	}
+}
+
+func TestParsePriceGuideLoadedColors(t *testing.T) {
+	defer func() { nameMap = buildNameMap() }()
+	loaded := BrickColor{id: 86, name: "LightBluishGray", c: color.RGBA{160, 165, 169, 255}}
+	RegisterColors([]BrickColor{loaded})
+	input := "Item No,Color,Avg Price\n3008,Light Bluish Gray,$0.04\n"
+	table, err := ParsePriceGuide(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	for _, c := range []BrickColor{MediumStoneGrey, loaded} {
+		if got := table.Price(OneByEight, c); got != 4 {
+			t.Errorf("for %v wanted 4 got %d", c.name, got)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// When the parts are already on hand, the question is not which color is nearest to each cell but
+// how to spend a fixed number of cells of each color. QuotaPosterize starts from the nearest color
+// for every cell, as posterizing does, and then takes cells out of each color that is over its
+// quota, cheapest first: a cell is moved to the nearest color that still has room, and the cells
+// that lose the least by moving are moved first. The colors that run out are said to bind; they
+// are the ones worth buying more of.
+
+// QuotaImage is an implementation of the Ideal interface in which no color is used for more cells
+// than its quota allows.
+type QuotaImage struct {
+	rows, cols  int
+	orientation ViewOrientation
+	palette     []BrickColor
+	distance    ColorDistance
+
+	// cells are the colors of the image, reduced to the cells of the mosaic
+	cells  map[Location]color.Color
+	colors map[Location]BrickColor
+	masked map[Location]bool
+
+	quota map[BrickColor]int
+	// used and demand count the cells given each color, and the cells that would have been given
+	// each color without the quotas.
+	used, demand map[BrickColor]int
+}
+
+// QuotaPosterize returns an Ideal representation of the image in which each color in the quota is
+// used for at most as many cells as its quota; colors in the palette without a quota may be used
+// for any number of cells. The image is reduced to cells, masked, and the colors are compared as
+// configured by the options; there is no dithering. It is an error if the quotas leave some cells
+// without a color.
+func QuotaPosterize(img image.Image, p color.Palette, rows, cols int, o ViewOrientation, quota map[BrickColor]int, opts PosterizeOptions) (*QuotaImage, error) {
+	qi := &QuotaImage{
+		rows:        rows,
+		cols:        cols,
+		orientation: o,
+		distance:    opts.Distance,
+		cells:       make(map[Location]color.Color),
+		colors:      make(map[Location]BrickColor),
+		masked:      make(map[Location]bool),
+		quota:       quota,
+		used:        make(map[BrickColor]int),
+		demand:      make(map[BrickColor]int),
+	}
+	for _, c := range p {
+		bc, ok := c.(BrickColor)
+		if !ok {
+			return nil, fmt.Errorf("palette color %v is not a BrickColor", c)
+		}
+		if !included(qi.palette, bc) {
+			qi.palette = append(qi.palette, bc)
+		}
+	}
+	if len(qi.palette) == 0 {
+		return nil, fmt.Errorf("empty palette")
+	}
+
+	ideal := &BrickImage{img: img, rows: rows, cols: cols, space: opts.Space, reducer: opts.Reducer}
+	distances := make(map[Location][]float64)
+	byColor := make(map[BrickColor][]Location)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			loc := Location{row, col}
+			if x0, y0, x1, y1 := ideal.cellBounds(row, col); opts.Mask.covers(img, x0, y0, x1, y1, opts.Distance) {
+				qi.masked[loc] = true
+				continue
+			}
+			cell := ideal.cellColor(row, col)
+			qi.cells[loc] = cell
+			distances[loc] = make([]float64, len(qi.palette))
+			for i, c := range qi.palette {
+				distances[loc][i] = opts.Distance.Distance(cell, c)
+			}
+			nearest := qi.palette[qi.nearest(distances[loc], nil)]
+			qi.colors[loc] = nearest
+			qi.used[nearest]++
+			qi.demand[nearest]++
+			byColor[nearest] = append(byColor[nearest], loc)
+		}
+	}
+
+	if capacity, unlimited := qi.capacity(); !unlimited && capacity < len(qi.cells) {
+		return nil, fmt.Errorf("quotas cover only %d of %d cells", capacity, len(qi.cells))
+	}
+
+	for i, c := range qi.palette {
+		for !qi.hasRoom(c, 0) {
+			// Price the move of every cell of c to the nearest color that still has room.
+			var moves []quotaMove
+			for _, loc := range byColor[c] {
+				if qi.colors[loc] != c {
+					continue
+				}
+				j := qi.nearest(distances[loc], func(j int) bool { return j != i && qi.hasRoom(qi.palette[j], 1) })
+				if j < 0 {
+					return nil, fmt.Errorf("no room for the cells over the quota of %v", c.name)
+				}
+				moves = append(moves, quotaMove{loc, j, distances[loc][j] - distances[loc][i]})
+			}
+			sort.Sort(byMoveCost(moves))
+			for _, m := range moves {
+				to := qi.palette[m.to]
+				if qi.hasRoom(c, 0) || !qi.hasRoom(to, 1) {
+					// Either c is within its quota, or the rest of the moves need to be priced again.
+					break
+				}
+				qi.colors[m.loc] = to
+				qi.used[c]--
+				qi.used[to]++
+				byColor[to] = append(byColor[to], m.loc)
+			}
+		}
+	}
+	return qi, nil
+}
+
+// quotaMove is the move of the cell at loc to the color at index to of the palette, and how much
+// further that color is from the cell than its current one.
+type quotaMove struct {
+	loc  Location
+	to   int
+	cost float64
+}
+
+type byMoveCost []quotaMove
+
+func (m byMoveCost) Len() int           { return len(m) }
+func (m byMoveCost) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byMoveCost) Less(i, j int) bool { return m[i].cost < m[j].cost }
+
+// capacity returns the number of cells the quotas of the palette colors cover, and whether some
+// palette color has no quota.
+func (qi *QuotaImage) capacity() (int, bool) {
+	total := 0
+	for _, c := range qi.palette {
+		n, ok := qi.quota[c]
+		if !ok {
+			return 0, true
+		}
+		total += n
+	}
+	return total, false
+}
+
+// hasRoom determines whether n more cells may be given color c without going over its quota.
+func (qi *QuotaImage) hasRoom(c BrickColor, n int) bool {
+	q, ok := qi.quota[c]
+	return !ok || qi.used[c]+n <= q
+}
+
+// nearest returns the index of the smallest distance whose color is allowed, or -1 if none are. A
+// nil allowed allows every color.
+func (qi *QuotaImage) nearest(distances []float64, allowed func(int) bool) int {
+	best := -1
+	for i, d := range distances {
+		if allowed != nil && !allowed(i) {
+			continue
+		}
+		if best < 0 || d < distances[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// NumRows returns the number of rows in the image.
+func (qi *QuotaImage) NumRows() int {
+	return qi.rows
+}
+
+// NumCols returns the number of columns in the image.
+func (qi *QuotaImage) NumCols() int {
+	return qi.cols
+}
+
+// Orientation returns the way in which the image is oriented.
+func (qi *QuotaImage) Orientation() ViewOrientation {
+	return qi.orientation
+}
+
+// Masked determines whether the row / col combination is left out of the mosaic.
+func (qi *QuotaImage) Masked(row, col int) bool {
+	return qi.masked[Location{row, col}]
+}
+
+// Color returns the color the row / col combination was given.
+func (qi *QuotaImage) Color(row, col int) BrickColor {
+	return qi.colors[Location{row, col}]
+}
+
+// Used returns the number of cells given color c.
+func (qi *QuotaImage) Used(c BrickColor) int {
+	return qi.used[c]
+}
+
+// Demand returns the number of cells that would have been given color c if there were no quotas.
+func (qi *QuotaImage) Demand(c BrickColor) int {
+	return qi.demand[c]
+}
+
+// Binding returns the colors whose quota kept cells from being given them, in palette order.
+func (qi *QuotaImage) Binding() []BrickColor {
+	var result []BrickColor
+	for _, c := range qi.palette {
+		if q, ok := qi.quota[c]; ok && qi.demand[c] > q {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// MeanColorDistance returns the average distance between the color of each cell of the image and
+// the brick color it was given. Masked cells are left out.
+func (qi *QuotaImage) MeanColorDistance() float64 {
+	if len(qi.colors) == 0 {
+		return 0
+	}
+	total := 0.0
+	for loc, c := range qi.colors {
+		total += qi.distance.Distance(qi.cells[loc], c)
+	}
+	return total / float64(len(qi.colors))
+}
+
+// ParseQuota reads the number of cells allowed in each color from a comma separated list of
+// color:count pairs, e.g.
+//
+//	Black:800,White:300
+func ParseQuota(s string) (map[BrickColor]int, error) {
+	result := make(map[BrickColor]int)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("wanted color:count got %q", pair)
+		}
+		c := ColorForName(strings.TrimSpace(parts[0]))
+		if c == nil {
+			return nil, fmt.Errorf("unknown color %q", parts[0])
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("for %v: bad count %q", c.name, parts[1])
+		}
+		result[*c] = n
+	}
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package BrickMosaic
+
+import (
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestQuotaPosterize(t *testing.T) {
+	// Six dark cells and four light ones, from darkest to lightest.
+	img := bands(2, []color.Color{
+		color.Gray{0}, color.Gray{20}, color.Gray{40}, color.Gray{60}, color.Gray{80}, color.Gray{100},
+		color.Gray{200}, color.Gray{220}, color.Gray{240}, color.Gray{255},
+	}, []int{1, 1, 1, 1, 1, 1, 1, 1, 1, 1})
+	p := color.Palette{Black, DarkGrey, White}
+	for _, test := range []struct {
+		name    string
+		quota   map[BrickColor]int
+		want    []BrickColor
+		binding []BrickColor
+	}{
+		{
+			"no quota",
+			nil,
+			[]BrickColor{Black, Black, Black, Black, DarkGrey, DarkGrey, White, White, White, White},
+			nil,
+		},
+		{
+			"darkest cells keep black",
+			map[BrickColor]int{Black: 2},
+			[]BrickColor{Black, Black, DarkGrey, DarkGrey, DarkGrey, DarkGrey, White, White, White, White},
+			[]BrickColor{Black},
+		},
+		{
+			"darkest cells move to white last",
+			map[BrickColor]int{Black: 2, DarkGrey: 0},
+			[]BrickColor{Black, Black, White, White, White, White, White, White, White, White},
+			[]BrickColor{Black, DarkGrey},
+		},
+		{
+			"quota with room",
+			map[BrickColor]int{White: 10},
+			[]BrickColor{Black, Black, Black, Black, DarkGrey, DarkGrey, White, White, White, White},
+			nil,
+		},
+	} {
+		qi, err := QuotaPosterize(img, p, 1, 10, StudsOut, test.quota, PosterizeOptions{Distance: CIEDE2000})
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		var got []BrickColor
+		for col := 0; col < qi.NumCols(); col++ {
+			got = append(got, qi.Color(0, col))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, PaletteString(test.want), PaletteString(got))
+		}
+		if got := qi.Binding(); !reflect.DeepEqual(got, test.binding) {
+			t.Errorf("for %q wanted binding %v got %v", test.name, PaletteString(test.binding), PaletteString(got))
+		}
+		for c, q := range test.quota {
+			if qi.Used(c) > q {
+				t.Errorf("for %q wanted at most %d %v got %d", test.name, q, c.name, qi.Used(c))
+			}
+		}
+	}
+
+	if _, err := QuotaPosterize(img, p, 1, 10, StudsOut, map[BrickColor]int{Black: 3, DarkGrey: 3, White: 3}, PosterizeOptions{}); err == nil {
+		t.Errorf("wanted an error for quotas covering 9 of 10 cells")
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	got, err := ParseQuota("Black:800, White:300")
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	if want := map[BrickColor]int{Black: 800, White: 300}; !reflect.DeepEqual(got, want) {
+		t.Errorf("wanted %v got %v", want, got)
+	}
+	for _, s := range []string{"Black", "Plaid:3", "Black:-1", "Black:lots"} {
+		if _, err := ParseQuota(s); err == nil {
+			t.Errorf("for %q wanted an error", s)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A wall whose vertical seams line up from one course to the next has nothing but the studs of a
+// single brick holding each column together, and falls apart when picked up. Real brick walls are
+// laid in a running bond: each seam sits over the middle of a brick in the course below.
+//
+// The running bond solver lays the wall one course at a time, from the bottom up. Each horizontal
+// run of cells in a course is split into pieces with a one dimensional dynamic program that
+// minimizes the cost of the pieces plus a penalty for every seam that lines up with a seam in the
+// course directly beneath it.
+//
+// In a StudsTop mosaic the courses are the rows of the grid, and the course beneath is the next
+// row down. In a StudsRight mosaic the studs face to the right, so the courses are the columns of
+// the grid, and the course beneath is the column to the left. The solver handles StudsRight by
+// turning the grid on its side, solving it as if it were StudsTop, and turning the result back.
+
+// DefaultSeamWeight is the default penalty, in cents, for each seam that lines up with the seam
+// beneath it.
+const DefaultSeamWeight = 10
+
+// RunningBondSolver returns a GridSolver that staggers the seams between pieces like a real brick
+// wall. The weight is the penalty, in cents, for every seam that lines up with a seam directly
+// beneath it; 0 ignores the seams entirely and only minimizes cost, while a large weight avoids
+// aligned seams whenever possible no matter the cost.
+//
+// StudsOut mosaics have no courses to stagger, so for them the solver is GreedyMinCostSolve.
+func RunningBondSolver(o ViewOrientation, weight int) GridSolver {
+	switch o {
+	case StudsTop:
+		return func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+			return runningBondSolve(g, pieces, weight)
+		}
+	case StudsRight:
+		return func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+			return studsRightRunningBondSolve(g, pieces, weight)
+		}
+	}
+	return GreedyMinCostSolve
+}
+
+// runningBondSolve lays the courses of a StudsTop grid from the bottom up.
+func runningBondSolve(g *Grid, pieces []MosaicPiece, weight int) (Solution, error) {
+	originalGrid := g.Clone()
+	locs := make(map[Location]MosaicPiece)
+	// Which piece covers each cell, so that we know where the seams are in the course beneath.
+	owner := newOwnerGrid(g.Rows, g.Cols)
+	var rects []MosaicPiece
+	for _, p := range pieces {
+		if len(p.Extent()) == p.Rows()*p.Cols() {
+			rects = append(rects, p)
+		}
+	}
+
+	for row := g.Rows - 1; row >= 0; row-- {
+		for start := 0; start < g.Cols; {
+			if g.Get(row, start) != ToBeFilled {
+				start++
+				continue
+			}
+			end := start
+			for end < g.Cols && g.Get(row, end) == ToBeFilled {
+				end++
+			}
+			for _, pl := range layCourse(g, &originalGrid, owner, rects, row, start, end, weight) {
+				locs[pl.origin] = pl.piece
+				for _, pieceLoc := range pl.piece.Extent() {
+					absLoc := pl.origin.Add(pieceLoc)
+					g.State[absLoc.Row][absLoc.Col] = Filled
+					owner[absLoc.Row][absLoc.Col] = len(locs)
+				}
+			}
+			start = end
+		}
+	}
+
+	// Anything the courses could not cover exactly is left to the greedy solver.
+	if g.Any(ToBeFilled) {
+		rest, _ := GreedyMinCostSolve(g, pieces)
+		for loc, p := range rest.Pieces {
+			locs[loc] = p
+		}
+	}
+	if g.Any(ToBeFilled) {
+		return Solution{originalGrid, locs}, fmt.Errorf("Following locations must still be filled: %v", g.Find(ToBeFilled))
+	}
+	return Solution{originalGrid, locs}, nil
+}
+
+// newOwnerGrid returns a grid of piece numbers, where 0 means no piece.
+func newOwnerGrid(rows, cols int) [][]int {
+	owner := make([][]int, rows)
+	for i := range owner {
+		owner[i] = make([]int, cols)
+	}
+	return owner
+}
+
+// seamBeneath determines whether there is a seam between columns col-1 and col in the course
+// beneath row. Two different pieces always form a seam, as does the edge of the region being
+// filled; if neither side belongs to this grid we cannot know, so it does not count.
+func seamBeneath(orig *Grid, owner [][]int, row, col int) bool {
+	below := row + 1
+	if below >= orig.Rows || col <= 0 || col >= orig.Cols {
+		return false
+	}
+	left, right := owner[below][col-1], owner[below][col]
+	leftOurs := orig.Get(below, col-1) == ToBeFilled
+	rightOurs := orig.Get(below, col) == ToBeFilled
+	if leftOurs && rightOurs {
+		return left != right
+	}
+	return leftOurs != rightOurs
+}
+
+// courseStep is an entry in the dynamic program over a single run of cells.
+type courseStep struct {
+	reached   bool
+	cost      int
+	numPieces int
+	prev      int
+	piece     MosaicPiece
+}
+
+// layCourse covers the cells [start, end) of the given row, whose pieces will sit with their
+// bottom edge on this row. It returns nothing if the run cannot be covered exactly.
+func layCourse(g, orig *Grid, owner [][]int, pieces []MosaicPiece, row, start, end, weight int) []placement {
+	steps := make([]courseStep, end-start+1)
+	steps[0].reached = true
+	for x := start; x < end; x++ {
+		from := steps[x-start]
+		if !from.reached {
+			continue
+		}
+		for _, p := range pieces {
+			next := x + p.Cols()
+			origin := Location{row - p.Rows() + 1, x}
+			if next > end || !g.PieceFits(p.Extent(), origin) {
+				continue
+			}
+			cost := from.cost + p.ApproximateCost()
+			if next < end && seamBeneath(orig, owner, row, next) {
+				cost += weight
+			}
+			to := &steps[next-start]
+			if !to.reached || cost < to.cost || (cost == to.cost && from.numPieces+1 < to.numPieces) {
+				*to = courseStep{true, cost, from.numPieces + 1, x, p}
+			}
+		}
+	}
+	if !steps[end-start].reached {
+		return nil
+	}
+	var result []placement
+	for x := end; x > start; x = steps[x-start].prev {
+		p := steps[x-start].piece
+		result = append(result, placement{origin: Location{row - p.Rows() + 1, steps[x-start].prev}, piece: p})
+	}
+	return result
+}
+
+// studsRightRunningBondSolve turns the grid a quarter turn so that its courses are rows with the
+// bottom course last, solves it, and turns the solution back.
+func studsRightRunningBondSolve(g *Grid, pieces []MosaicPiece, weight int) (Solution, error) {
+	originalGrid := g.Clone()
+	// Cell (row, col) of the original is cell (Cols-1-col, row) of the turned grid.
+	turned := NewGrid(g.Cols, g.Rows)
+	for row := 0; row < g.Rows; row++ {
+		for col := 0; col < g.Cols; col++ {
+			turned.Set(g.Cols-1-col, row, g.Get(row, col))
+		}
+	}
+	turnedPieces := make([]MosaicPiece, len(pieces))
+	originals := make(map[MosaicPiece]MosaicPiece)
+	for i, p := range pieces {
+		turnedPieces[i] = mosaicPiece{Brick: p, Rect: RectPiece{p.Cols(), p.Rows()}}
+		originals[turnedPieces[i]] = p
+	}
+
+	solution, err := runningBondSolve(&turned, turnedPieces, weight)
+
+	locs := make(map[Location]MosaicPiece)
+	for origin, tp := range solution.Pieces {
+		p := originals[tp]
+		loc := Location{origin.Col, g.Cols - origin.Row - tp.Rows()}
+		locs[loc] = p
+		for _, pieceLoc := range p.Extent() {
+			absLoc := loc.Add(pieceLoc)
+			g.State[absLoc.Row][absLoc.Col] = Filled
+		}
+	}
+	if err != nil {
+		return Solution{originalGrid, locs}, fmt.Errorf("Following locations must still be filled: %v", g.Find(ToBeFilled))
+	}
+	return Solution{originalGrid, locs}, nil
+}
+
+// alignedSeams counts the seams in the solution that line up with a seam in the course beneath
+// them, using the same definition of a seam as the running bond solver.
+func alignedSeams(s Solution, o ViewOrientation) int {
+	return newSeamTracker(s, o).aligned()
+}
+
+// seamTracker counts the seams of a solution that line up with a seam in the course beneath them,
+// and keeps the count up to date as pieces are taken out and put in, so that a move can be scored
+// by looking only at the cells it touches. A StudsRight grid is turned the same way the solver
+// turns it, so that its courses are rows. Other orientations have no courses; their tracker is nil,
+// which is valid and counts no seams.
+type seamTracker struct {
+	turned bool
+	orig   Grid
+	// owner numbers the piece covering each cell of the turned grid, or is 0 if there is none.
+	owner [][]int
+	next  int
+	count int
+}
+
+func newSeamTracker(s Solution, o ViewOrientation) *seamTracker {
+	if o != StudsTop && o != StudsRight {
+		return nil
+	}
+	t := &seamTracker{turned: o == StudsRight}
+	rows, cols := s.Original.Rows, s.Original.Cols
+	if t.turned {
+		// Cell (row, col) of the original is cell (cols-1-col, row) of the turned grid.
+		t.orig = NewGrid(cols, rows)
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				t.orig.Set(cols-1-col, row, s.Original.Get(row, col))
+			}
+		}
+	} else {
+		t.orig = s.Original.Clone()
+	}
+	t.owner = newOwnerGrid(t.orig.Rows, t.orig.Cols)
+	for _, origin := range sortedLocations(s.Pieces) {
+		t.put(origin, s.Pieces[origin])
+	}
+	for row := 0; row < t.orig.Rows; row++ {
+		for col := 1; col < t.orig.Cols; col++ {
+			if t.alignedAt(row, col) {
+				t.count++
+			}
+		}
+	}
+	return t
+}
+
+// aligned returns the number of seams that line up with the seam beneath them.
+func (t *seamTracker) aligned() int {
+	if t == nil {
+		return 0
+	}
+	return t.count
+}
+
+// replace takes out the removed pieces and puts in the added ones, keyed by their origins.
+func (t *seamTracker) replace(removed, added map[Location]MosaicPiece) {
+	if t == nil {
+		return
+	}
+	var cells []Location
+	for origin, p := range removed {
+		for _, rel := range p.Extent() {
+			cells = append(cells, origin.Add(rel))
+		}
+	}
+	before := t.alignedNear(cells)
+	for origin, p := range removed {
+		t.fill(origin, p, 0)
+	}
+	for _, origin := range sortedLocations(added) {
+		t.put(origin, added[origin])
+	}
+	t.count += t.alignedNear(cells) - before
+}
+
+// put numbers the cells of the piece at origin as a new piece.
+func (t *seamTracker) put(origin Location, p MosaicPiece) {
+	t.next++
+	t.fill(origin, p, t.next)
+}
+
+// fill sets the owner of the cells of the piece at origin.
+func (t *seamTracker) fill(origin Location, p MosaicPiece, id int) {
+	for _, rel := range p.Extent() {
+		c := t.cell(origin.Add(rel))
+		t.owner[c.Row][c.Col] = id
+	}
+}
+
+// cell returns the cell of the turned grid that a cell of the solution is turned to.
+func (t *seamTracker) cell(loc Location) Location {
+	if t.turned {
+		return Location{t.orig.Rows - 1 - loc.Col, loc.Row}
+	}
+	return loc
+}
+
+// alignedNear counts the aligned seams whose owners include any of the cells of the solution: the
+// seams on either side of each cell, in its course and the course above.
+func (t *seamTracker) alignedNear(cells []Location) int {
+	seams := make(map[Location]bool)
+	for _, loc := range cells {
+		c := t.cell(loc)
+		for _, row := range []int{c.Row - 1, c.Row} {
+			for _, col := range []int{c.Col, c.Col + 1} {
+				if row >= 0 && row < t.orig.Rows && col >= 1 && col < t.orig.Cols {
+					seams[Location{row, col}] = true
+				}
+			}
+		}
+	}
+	count := 0
+	for seam := range seams {
+		if t.alignedAt(seam.Row, seam.Col) {
+			count++
+		}
+	}
+	return count
+}
+
+// alignedAt determines whether there is a seam between columns col-1 and col of the turned grid
+// in the given row that lines up with a seam beneath it.
+func (t *seamTracker) alignedAt(row, col int) bool {
+	left, right := t.owner[row][col-1], t.owner[row][col]
+	if left == 0 || right == 0 || left == right {
+		return false
+	}
+	// Within the height of a brick the seam beneath is the very same seam.
+	if row+1 < t.orig.Rows && t.owner[row+1][col-1] == left && t.owner[row+1][col] == right {
+		return false
+	}
+	return seamBeneath(&t.orig, t.owner, row, col)
+}
+
+// sortedLocations returns the keys of the map in row major order.
+func sortedLocations(pieces map[Location]MosaicPiece) []Location {
+	locs := make([]Location, 0, len(pieces))
+	for loc := range pieces {
+		locs = append(locs, loc)
+	}
+	sort.Sort(rowMajor(locs))
+	return locs
+}
+
+// rowMajor sorts locations top to bottom, left to right.
+type rowMajor []Location
+
+func (r rowMajor) Len() int {
+	return len(r)
+}
+
+func (r rowMajor) Less(i, j int) bool {
+	if r[i].Row != r[j].Row {
+		return r[i].Row < r[j].Row
+	}
+	return r[i].Col < r[j].Col
+}
+
+func (r rowMajor) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
@@ -0,0 +1,65 @@
+package BrickMosaic
+
+import (
+	"testing"
+)
+
+func TestRunningBondSolverStaggersSeams(t *testing.T) {
+	plates := []MosaicPiece{
+		StudsTopPiece(OneByFourPlate),
+		StudsTopPiece(OneByTwoPlate),
+		StudsTopPiece(OneByOnePlate),
+	}
+	for _, test := range []struct {
+		name    string
+		weight  int
+		aligned int
+	}{
+		{"cost only", 0, 1},
+		{"default weight", DefaultSeamWeight, 0},
+	} {
+		g := WithState(2, 6, ToBeFilled)
+		got, err := RunningBondSolver(StudsTop, test.weight)(&g, plates)
+		if err != nil {
+			t.Fatalf("for %q wanted no error got %v", test.name, err)
+		}
+		checkCovered(t, test.name, got)
+		// Both courses are a 1x4 and a 1x2 no matter how the seams fall.
+		if got.Cost() != 10 {
+			t.Errorf("for %q wanted cost 10 got %d", test.name, got.Cost())
+		}
+		if n := alignedSeams(got, StudsTop); n != test.aligned {
+			t.Errorf("for %q wanted %d aligned seams got %d: %v", test.name, test.aligned, n, got)
+		}
+	}
+}
+
+func TestRunningBondSolverStudsRight(t *testing.T) {
+	pieces := PiecesForOrientation(StudsRight, allBricks())
+	seams := make(map[int]int)
+	for _, weight := range []int{0, 1000} {
+		g := WithState(8, 9, ToBeFilled)
+		got, err := RunningBondSolver(StudsRight, weight)(&g, pieces)
+		if err != nil {
+			t.Fatalf("for weight %d wanted no error got %v", weight, err)
+		}
+		checkCovered(t, "studs right", got)
+		seams[weight] = alignedSeams(got, StudsRight)
+	}
+	if seams[1000] >= seams[0] {
+		t.Errorf("penalizing seams should reduce aligned seams; got %v", seams)
+	}
+}
+
+func TestRunningBondSolverFillsRegions(t *testing.T) {
+	pieces := PiecesForOrientation(StudsTop, allBricks())
+	g := WithState(7, 10, ToBeFilled)
+	// A hole in the middle of the wall, as if another color were there.
+	g.Set(3, 4, Empty)
+	g.Set(3, 5, Empty)
+	got, err := RunningBondSolver(StudsTop, DefaultSeamWeight)(&g, pieces)
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	checkCovered(t, "with hole", got)
+}
@@ -0,0 +1,149 @@
+package BrickMosaic
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Stock is a finite supply of parts: how many of each Brick there are in each BrickColor. When a
+// Stock is given to CreateGridMosaic, the plan never uses more of a part than the stock holds; any
+// color that cannot be finished is reported in both the error and the plan's Shortfall.
+type Stock struct {
+	counts map[BrickColor]map[Brick]int
+}
+
+// NewStock returns an empty stock.
+func NewStock() *Stock {
+	return &Stock{make(map[BrickColor]map[Brick]int)}
+}
+
+// Add adds n of the given part in the given color to the stock.
+func (s *Stock) Add(c BrickColor, b Brick, n int) {
+	if s.counts[c] == nil {
+		s.counts[c] = make(map[Brick]int)
+	}
+	s.counts[c][BaseBrick(b)] += n
+}
+
+// Count returns how many of the given part in the given color are in the stock.
+func (s *Stock) Count(c BrickColor, b Brick) int {
+	return s.counts[c][BaseBrick(b)]
+}
+
+// remaining returns a stock holding what is left of the parts in color c once the pieces already
+// placed in that color are taken out of it.
+func (s *Stock) remaining(c BrickColor, used []PlacedBrick) *Stock {
+	result := NewStock()
+	for b, n := range s.counts[c] {
+		result.Add(c, b, n)
+	}
+	for _, p := range used {
+		if p.Color == c && result.Count(c, p.Shape) > 0 {
+			result.Add(c, p.Shape, -1)
+		}
+	}
+	return result
+}
+
+// ParseStock reads a stock from CSV records of the form
+//
+//	color,part id,count
+//
+// e.g. "Black,3005,800". Colors are looked up with ColorForName, and parts by their Id among the
+// given bricks. Lines starting with # are ignored.
+func ParseStock(r io.Reader, bricks []Brick) (*Stock, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[string]Brick)
+	for _, b := range bricks {
+		byId[b.Id()] = b
+	}
+	stock := NewStock()
+	for i, record := range records {
+		c := ColorForName(strings.TrimSpace(record[0]))
+		if c == nil {
+			return nil, fmt.Errorf("line %d: unknown color %q", i+1, record[0])
+		}
+		b, ok := byId[strings.TrimSpace(record[1])]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown part %q", i+1, record[1])
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(record[2]))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("line %d: invalid count %q", i+1, record[2])
+		}
+		stock.Add(*c, b, n)
+	}
+	return stock, nil
+}
+
+// solveWithStock solves the grid of the given color using no more parts than the stock holds.
+// Whenever the solver uses more of a part than there is, the extra pieces are taken back out and
+// their cells are solved again without that part, so that smaller parts are used instead. Any
+// cells that still cannot be covered are left unfilled; the parts needed to cover them are
+// returned as the shortfall. It stops once the context is done, returning the context's error.
+func solveWithStock(ctx context.Context, g *Grid, solver GridSolver, pieces []MosaicPiece, c BrickColor, stock *Stock) (Solution, []Brick, error) {
+	originalGrid := g.Clone()
+	locs := make(map[Location]MosaicPiece)
+	remaining := make(map[Brick]int)
+	for _, p := range pieces {
+		remaining[BaseBrick(p)] = stock.Count(c, p)
+	}
+
+	for g.Any(ToBeFilled) {
+		if err := ctx.Err(); err != nil {
+			return Solution{originalGrid, locs}, nil, err
+		}
+		var available []MosaicPiece
+		for _, p := range pieces {
+			if remaining[BaseBrick(p)] > 0 {
+				available = append(available, p)
+			}
+		}
+		if len(available) == 0 {
+			break
+		}
+		work := g.Clone()
+		solution, _ := solver(&work, available)
+		exhausted := false
+		for _, origin := range sortedLocations(solution.Pieces) {
+			p := solution.Pieces[origin]
+			if remaining[BaseBrick(p)] == 0 {
+				exhausted = true
+				continue
+			}
+			remaining[BaseBrick(p)]--
+			locs[origin] = p
+			for _, rel := range p.Extent() {
+				abs := origin.Add(rel)
+				g.State[abs.Row][abs.Col] = Filled
+			}
+		}
+		// If the solver kept within the stock, another attempt would give the same answer.
+		if !exhausted {
+			break
+		}
+	}
+
+	if !g.Any(ToBeFilled) {
+		return Solution{originalGrid, locs}, nil, nil
+	}
+	// Whatever is left is what we would need to buy to finish the job.
+	missing := g.Clone()
+	extra, _ := solver(&missing, pieces)
+	var shortfall []Brick
+	for _, origin := range sortedLocations(extra.Pieces) {
+		shortfall = append(shortfall, BaseBrick(extra.Pieces[origin]))
+	}
+	return Solution{originalGrid, locs}, shortfall, fmt.Errorf("not enough parts in %v; following locations must still be filled: %v", c.name, g.Find(ToBeFilled))
+}
@@ -0,0 +1,79 @@
+package BrickMosaic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCreateGridMosaicWithStock(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		stock     map[Brick]int
+		pieces    int
+		shortfall []string
+	}{
+		{
+			name:   "falls back to smaller pieces",
+			stock:  map[Brick]int{OneByTwo: 1, OneByOne: 2},
+			pieces: 3,
+		},
+		{
+			name:      "reports what is missing",
+			stock:     map[Brick]int{OneByOne: 1},
+			pieces:    1,
+			shortfall: []string{"3622"},
+		},
+	} {
+		stock := NewStock()
+		for b, n := range test.stock {
+			stock.Add(BrightRed, b, n)
+		}
+		plan, err := CreateGridMosaic(uniformIdeal{StudsOut, 1, 4, BrightRed}, GreedySolve, MosaicOptions{Stock: stock})
+		if (err != nil) != (len(test.shortfall) > 0) {
+			t.Errorf("for %q wanted an error only when parts are missing, got %v", test.name, err)
+		}
+
+		used := make(map[Brick]int)
+		for _, p := range plan.Pieces() {
+			used[BaseBrick(p.Shape)]++
+		}
+		if len(plan.Pieces()) != test.pieces {
+			t.Errorf("for %q wanted %d pieces got %v", test.name, test.pieces, used)
+		}
+		for b, n := range used {
+			if n > test.stock[b] {
+				t.Errorf("for %q used %d of %v but only %d in stock", test.name, n, b.Name(), test.stock[b])
+			}
+		}
+		var shortfall []string
+		for _, b := range plan.Shortfall().PiecesForColor(BrightRed) {
+			shortfall = append(shortfall, b.Id())
+		}
+		if !reflect.DeepEqual(shortfall, test.shortfall) {
+			t.Errorf("for %q wanted shortfall %v got %v", test.name, test.shortfall, shortfall)
+		}
+	}
+}
+
+func TestParseStock(t *testing.T) {
+	stock, err := ParseStock(strings.NewReader("# color,part,count\nBrightRed,3005,12\nBrightRed, 3005, 3\n"), allBricks())
+	if err != nil {
+		t.Fatalf("wanted no error got %v", err)
+	}
+	if got := stock.Count(BrightRed, StudsOutPiece(OneByOne)); got != 15 {
+		t.Errorf("wanted 15 1x1s got %d", got)
+	}
+
+	for _, input := range []string{
+		"BrightRed,3005",
+		"Not a color,3005,1",
+		"BrightRed,0000,1",
+		"BrightRed,3005,-1",
+		"BrightRed,3005,many",
+	} {
+		if _, err := ParseStock(strings.NewReader(input), allBricks()); err == nil {
+			t.Errorf("for %q wanted an error", input)
+		}
+	}
+}
@@ -9,7 +9,11 @@ import (
 	"github.com/ajstarks/svgo"
 )
 
-type SVGRenderer struct{}
+type SVGRenderer struct {
+	// HighlightIslands outlines every group of bricks that is not connected to the largest group,
+	// so that they can be fixed before the parts are ordered.
+	HighlightIslands bool
+}
 
 // Upper left origin
 func BoundingBox(p Piece, origin Location) (minRow, minCol, maxRow, maxCol int) {
@@ -83,28 +87,22 @@ func DoRender(p Plan, canvas *svg.SVG) {
 	canvas.Gend()
 
 	canvas.Gid("block_outlines")
-	// Draw outlines around each piece
+	// Draw outlines around each piece. The class names the part, which is the same whichever way
+	// the piece is turned.
 	for _, piece := range p.Pieces() {
-		loc := piece.Origin
-		minRow, minCol, maxRow, maxCol := BoundingBox(piece, loc)
-
-		// Offset by one because we draw to where it ends. e.g. if it takes up only one
-		// row or column, we still need to draw it as if it went into right before the
-		// next row or column.
-		startX := minCol * brickWidth
-		endX := (maxCol + 1) * brickWidth
-
-		startY := minRow * brickHeight
-		endY := (maxRow + 1) * brickHeight
-
-		width := endX - startX
-		height := endY - startY
-
 		style := "fill='none' stroke='gray'"
-		canvas.Rect(startX, startY, width, height, style)
+		if piece.Shape != nil {
+			style = fmt.Sprintf("class='part-%v' %v", BaseBrick(piece.Shape).Id(), style)
+		}
+		drawOutline(canvas, piece, brickWidth, brickHeight, style)
 	}
 	canvas.Gend()
 
+	if l, ok := p.(LayeredPlan); ok {
+		renderFrame(l.Layer(FrameLayer), canvas, brickWidth, brickHeight)
+		renderBaseplates(l.Layer(BaseplateLayer), canvas, brickWidth, brickHeight)
+	}
+
 	/*
 		canvas.Gid("gridlines")
 		imageWidth := brickWidth * p.Orig().NumCols()
@@ -140,15 +138,149 @@ func DoRender(p Plan, canvas *svg.SVG) {
 		canvas.Gend()*/
 }
 
+// drawOutline draws the outline of the piece on the canvas in the given style.
+func drawOutline(canvas *svg.SVG, piece PlacedBrick, brickWidth, brickHeight int, style string) {
+	minRow, minCol, maxRow, maxCol := BoundingBox(piece, piece.Origin)
+	if len(piece.Extent()) != (maxRow-minRow+1)*(maxCol-minCol+1) {
+		drawShapeOutline(canvas, piece, brickWidth, brickHeight, style)
+		return
+	}
+
+	// Offset by one because we draw to where it ends. e.g. if it takes up only one
+	// row or column, we still need to draw it as if it went into right before the
+	// next row or column.
+	startX := minCol * brickWidth
+	endX := (maxCol + 1) * brickWidth
+
+	startY := minRow * brickHeight
+	endY := (maxRow + 1) * brickHeight
+
+	canvas.Rect(startX, startY, endX-startX, endY-startY, style)
+}
+
+// drawShapeOutline draws the true outline of a piece that is not a rectangle, e.g. a corner plate.
+// Every edge of a covered cell that does not border another covered cell is part of the outline.
+func drawShapeOutline(canvas *svg.SVG, piece PlacedBrick, brickWidth, brickHeight int, style string) {
+	covered := make(map[Location]bool)
+	for _, loc := range piece.Extent() {
+		covered[piece.Origin.Add(loc)] = true
+	}
+	var path bytes.Buffer
+	for _, loc := range piece.Extent() {
+		cell := piece.Origin.Add(loc)
+		x0, y0 := cell.Col*brickWidth, cell.Row*brickHeight
+		x1, y1 := x0+brickWidth, y0+brickHeight
+		for _, edge := range []struct {
+			neighbor     Location
+			fromX, fromY int
+			toX, toY     int
+		}{
+			{Location{-1, 0}, x0, y0, x1, y0},
+			{Location{0, 1}, x1, y0, x1, y1},
+			{Location{1, 0}, x1, y1, x0, y1},
+			{Location{0, -1}, x0, y1, x0, y0},
+		} {
+			if !covered[cell.Add(edge.neighbor)] {
+				fmt.Fprintf(&path, "M%d %d L%d %d ", edge.fromX, edge.fromY, edge.toX, edge.toY)
+			}
+		}
+	}
+	canvas.Path(path.String(), style)
+}
+
+// renderFrame draws the pieces of the frame, with their outlines, in a group of their own.
+func renderFrame(frame []PlacedBrick, canvas *svg.SVG, brickWidth, brickHeight int) {
+	if len(frame) == 0 {
+		return
+	}
+	canvas.Gid("frame")
+	for _, piece := range frame {
+		r, g, b, _ := piece.Color.RGBA()
+		colorStr := canvas.RGB(int(r/255), int(g/255), int(b/255))
+		for _, loc := range piece.Extent() {
+			translated := piece.Origin.Add(loc)
+			canvas.Rect(translated.Col*brickWidth, translated.Row*brickHeight, brickWidth, brickHeight, colorStr)
+		}
+		style := fmt.Sprintf("class='part-%v' fill='none' stroke='gray'", BaseBrick(piece.Shape).Id())
+		drawOutline(canvas, piece, brickWidth, brickHeight, style)
+	}
+	canvas.Gend()
+}
+
+// drawnArea returns the upper left cell and the number of rows and columns the rendering of the
+// plan covers: the mosaic and its frame, if it has one.
+func drawnArea(p Plan) (upperLeft Location, rows, cols int) {
+	lowerRight := Location{p.Orig().NumRows() - 1, p.Orig().NumCols() - 1}
+	if l, ok := p.(LayeredPlan); ok {
+		for _, piece := range l.Layer(FrameLayer) {
+			minRow, minCol, maxRow, maxCol := BoundingBox(piece, piece.Origin)
+			if minRow < upperLeft.Row {
+				upperLeft.Row = minRow
+			}
+			if minCol < upperLeft.Col {
+				upperLeft.Col = minCol
+			}
+			if maxRow > lowerRight.Row {
+				lowerRight.Row = maxRow
+			}
+			if maxCol > lowerRight.Col {
+				lowerRight.Col = maxCol
+			}
+		}
+	}
+	return upperLeft, lowerRight.Row - upperLeft.Row + 1, lowerRight.Col - upperLeft.Col + 1
+}
+
+// renderBaseplates outlines the section of the mosaic each baseplate is under, and numbers it in
+// the middle.
+func renderBaseplates(plates []PlacedBrick, canvas *svg.SVG, brickWidth, brickHeight int) {
+	if len(plates) == 0 {
+		return
+	}
+	canvas.Gid("baseplates")
+	for _, plate := range plates {
+		minRow, minCol, maxRow, maxCol := BoundingBox(plate, plate.Origin)
+		canvas.Gid(fmt.Sprintf("baseplate-%d", plate.Id))
+		drawOutline(canvas, plate, brickWidth, brickHeight, "fill='none' stroke='black' stroke-width='3'")
+		x := (minCol + maxCol + 1) * brickWidth / 2
+		y := (minRow + maxRow + 1) * brickHeight / 2
+		canvas.Text(x, y, fmt.Sprint(plate.Id), "text-anchor='middle' dominant-baseline='middle' font-size='48' fill='black' fill-opacity='0.5'")
+		canvas.Gend()
+	}
+	canvas.Gend()
+}
+
+// RenderIslands outlines every brick that is not connected to the largest group of bricks in
+// the plan. Each island gets its own group.
+func RenderIslands(p Plan, canvas *svg.SVG) {
+	brickWidth, brickHeight := GetDimensionsForBlock(p.Orig().Orientation())
+	canvas.Gid("islands")
+	for i, island := range AnalyzeConnectivity(p).Islands() {
+		canvas.Gid(fmt.Sprintf("island-%d", i+1))
+		for _, piece := range island {
+			drawOutline(canvas, piece, brickWidth, brickHeight, "fill='none' stroke='red' stroke-width='2'")
+		}
+		canvas.Gend()
+	}
+	canvas.Gend()
+}
+
 func (r SVGRenderer) Render(p Plan) string {
 	var buf bytes.Buffer
 	canvas := svg.New(&buf)
 	blockWidth, blockHeight := GetDimensionsForBlock(p.Orig().Orientation())
-	width := blockWidth * p.Orig().NumCols()
-	height := blockHeight * p.Orig().NumRows()
+	upperLeft, rows, cols := drawnArea(p)
+	width := blockWidth * cols
+	height := blockHeight * rows
 	canvas.Start(width, height)
 	canvas.Title("Grid")
+	// Shift everything over to make room for a frame.
+	canvas.Translate(-upperLeft.Col*blockWidth, -upperLeft.Row*blockHeight)
 	DoRender(p, canvas)
+	if r.HighlightIslands {
+		RenderIslands(p, canvas)
+	}
+	canvas.Gend()
 	canvas.End()
 	return buf.String()
 }
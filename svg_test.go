@@ -2,6 +2,7 @@ package BrickMosaic
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -11,3 +12,34 @@ func TestSVGRender(t *testing.T) {
 	res := svg.Render(p)
 	fmt.Println(res)
 }
+
+func TestSVGRenderIslands(t *testing.T) {
+	plan := brickPlan{uniformIdeal{StudsTop, 6, 8, BrightRed}, []PlacedBrick{
+		placeBrick(1, StudsTop, OneByFour, Location{0, 0}),
+		placeBrick(2, StudsTop, OneByFour, Location{3, 0}),
+		placeBrick(3, StudsTop, OneByFour, Location{3, 4}),
+	}}
+	res := SVGRenderer{HighlightIslands: true}.Render(plan)
+	if !strings.Contains(res, `id="island-1"`) {
+		t.Errorf("wanted the island to be highlighted; got %v", res)
+	}
+	if strings.Contains(res, `id="island-2"`) {
+		t.Errorf("wanted only one island; got %v", res)
+	}
+}
+
+func TestSVGRenderCornerOutline(t *testing.T) {
+	plan := brickPlan{uniformIdeal{StudsOut, 2, 2, BrightRed}, []PlacedBrick{
+		placeBrick(1, StudsOut, CornerPlate, Location{0, 0}),
+		placeBrick(2, StudsOut, OneByOnePlate, Location{1, 1}),
+	}}
+	res := SVGRenderer{}.Render(plan)
+	// The corner is outlined by eight edges of its three cells; the 1x1 by a rectangle.
+	want := `class='part-2420' fill='none' stroke='gray'`
+	if !strings.Contains(res, "<path") || !strings.Contains(res, want) {
+		t.Errorf("wanted the true outline of the corner; got %v", res)
+	}
+	if got := strings.Count(res, " L"); got != 8 {
+		t.Errorf("wanted 8 edges in the outline got %d", got)
+	}
+}
@@ -16,8 +16,12 @@ func (t *WriterRenderer) Render(p Plan) string {
 	t.buff.Reset()
 	for row := 0; row < p.Orig().NumRows(); row++ {
 		for col := 0; col < p.Orig().NumCols(); col++ {
-			piece := p.Piece(row, col)
-			_, err := io.WriteString(&t.buff, fmt.Sprintf("%03d ", piece.Id))
+			// Masked cells have no piece, so they are left blank rather than shown as piece 0.
+			cell := "    "
+			if !isMasked(p.Orig(), row, col) {
+				cell = fmt.Sprintf("%03d ", p.Piece(row, col).Id)
+			}
+			_, err := io.WriteString(&t.buff, cell)
 			if err != nil {
 				panic("couldn't write string")
 			}
@@ -49,6 +49,10 @@ func (f *fakePlan) Inventory() Inventory {
 	return Inventory{}
 }
 
+func (f *fakePlan) Shortfall() Inventory {
+	return Inventory{}
+}
+
 func TestTerminalRender(t *testing.T) {
 	w := WriterRenderer{}
 	fmt.Println(w.Render(&fakePlan{}))
@@ -74,3 +74,22 @@ func TranslateAbsoluteOrigin(absLoc Location, p MosaicPiece, pt AnchorPoint) Loc
 	}
 	panic("Shouldn't reach here")
 }
+
+// AnchorCell returns the cell of the extent that sits at the anchor point: the covered cell
+// closest to that corner, looking at the nearest column first. For a rectangle this is the corner
+// itself; for a piece such as a corner plate, whose corner may be missing, it is the cell next to it.
+func AnchorCell(extent []Location, pt AnchorPoint) Location {
+	left := pt == UpperLeft || pt == LowerLeft
+	top := pt == UpperLeft || pt == UpperRight
+	a := extent[0]
+	for _, loc := range extent[1:] {
+		if loc.Col != a.Col {
+			if (loc.Col < a.Col) == left {
+				a = loc
+			}
+		} else if loc.Row != a.Row && (loc.Row < a.Row) == top {
+			a = loc
+		}
+	}
+	return a
+}
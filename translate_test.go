@@ -99,3 +99,25 @@ func TestTranslateAbsoluteOrigin(t *testing.T) {
 		}
 	}
 }
+
+func TestAnchorCell(t *testing.T) {
+	rect := RectPiece{2, 3}.Extent()
+	// A shape missing its upper left and lower right corners.
+	shape := []Location{{0, 1}, {0, 2}, {1, 0}, {1, 1}}
+	for _, test := range []struct {
+		pt         AnchorPoint
+		rect, want Location
+	}{
+		{UpperLeft, Location{0, 0}, Location{1, 0}},
+		{UpperRight, Location{0, 2}, Location{0, 2}},
+		{LowerRight, Location{1, 2}, Location{0, 2}},
+		{LowerLeft, Location{1, 0}, Location{1, 0}},
+	} {
+		if got := AnchorCell(rect, test.pt); got != test.rect {
+			t.Errorf("for rectangle at %v wanted %v got %v", test.pt, test.rect, got)
+		}
+		if got := AnchorCell(shape, test.pt); got != test.want {
+			t.Errorf("for shape at %v wanted %v got %v", test.pt, test.want, got)
+		}
+	}
+}
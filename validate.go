@@ -0,0 +1,101 @@
+package BrickMosaic
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Problem is the kind of mistake a Violation describes.
+type Problem int
+
+const (
+	// Uncovered means no brick covers the location.
+	Uncovered Problem = iota
+	// Overlap means more than one brick covers the location.
+	Overlap
+	// WrongColor means the brick covering the location is not the color the Ideal calls for.
+	WrongColor
+	// OutOfBounds means the brick sticks out of the mosaic at the location, either past its edge
+	// or into a masked cell.
+	OutOfBounds
+)
+
+func (p Problem) String() string {
+	switch p {
+	case Uncovered:
+		return "uncovered"
+	case Overlap:
+		return "overlap"
+	case WrongColor:
+		return "wrong color"
+	case OutOfBounds:
+		return "out of bounds"
+	}
+	return fmt.Sprintf("Problem(%d)", int(p))
+}
+
+// Violation is a single location at which a plan does not match its Ideal.
+type Violation struct {
+	Problem Problem
+	Loc     Location
+	// Bricks are the bricks at fault, if any. Uncovered locations have none; overlapping locations
+	// have every brick covering them.
+	Bricks []PlacedBrick
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%v at %v", v.Problem, v.Loc)
+}
+
+// ValidatePlan checks that every location of the plan's Ideal that is not masked is covered by
+// exactly one brick of the right color, and that no brick sticks out of bounds. It returns every
+// violation, ordered by location, top to bottom, left to right; a valid plan has none.
+func ValidatePlan(p Plan) []Violation {
+	ideal := p.Orig()
+	bricks := make([]PlacedBrick, len(p.Pieces()))
+	copy(bricks, p.Pieces())
+	sort.Sort(placedRowMajor(bricks))
+
+	var violations []Violation
+	covering := make(map[Location][]PlacedBrick)
+	for _, b := range bricks {
+		for _, rel := range b.Extent() {
+			loc := b.Origin.Add(rel)
+			if loc.Row < 0 || loc.Row >= ideal.NumRows() || loc.Col < 0 || loc.Col >= ideal.NumCols() || isMasked(ideal, loc.Row, loc.Col) {
+				violations = append(violations, Violation{OutOfBounds, loc, []PlacedBrick{b}})
+				continue
+			}
+			covering[loc] = append(covering[loc], b)
+			if ideal.Color(loc.Row, loc.Col) != b.Color {
+				violations = append(violations, Violation{WrongColor, loc, []PlacedBrick{b}})
+			}
+		}
+	}
+	for row := 0; row < ideal.NumRows(); row++ {
+		for col := 0; col < ideal.NumCols(); col++ {
+			loc := Location{row, col}
+			switch n := len(covering[loc]); {
+			case n == 0 && !isMasked(ideal, row, col):
+				violations = append(violations, Violation{Uncovered, loc, nil})
+			case n > 1:
+				violations = append(violations, Violation{Overlap, loc, covering[loc]})
+			}
+		}
+	}
+	sort.Stable(byViolationLocation(violations))
+	return violations
+}
+
+type byViolationLocation []Violation
+
+func (b byViolationLocation) Len() int {
+	return len(b)
+}
+
+func (b byViolationLocation) Less(i, j int) bool {
+	return rowMajor{b[i].Loc, b[j].Loc}.Less(0, 1)
+}
+
+func (b byViolationLocation) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
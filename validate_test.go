@@ -0,0 +1,70 @@
+package BrickMosaic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidatePlan(t *testing.T) {
+	blue := placeBrick(2, StudsOut, OneByOne, Location{0, 3})
+	blue.Color = BrightBlue
+	for _, test := range []struct {
+		name   string
+		bricks []PlacedBrick
+		want   []string
+	}{
+		{
+			name: "valid",
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsOut, OneByFour, Location{0, 0}),
+			},
+		},
+		{
+			name: "hole",
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsOut, OneByTwo, Location{0, 0}),
+				placeBrick(2, StudsOut, OneByOne, Location{0, 3}),
+			},
+			want: []string{"uncovered at {0 2}"},
+		},
+		{
+			name: "overlap and out of bounds",
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsOut, OneByThree, Location{0, 0}),
+				placeBrick(2, StudsOut, OneByThree, Location{0, 2}),
+			},
+			want: []string{"overlap at {0 2}", "out of bounds at {0 4}"},
+		},
+		{
+			name: "wrong color",
+			bricks: []PlacedBrick{
+				placeBrick(1, StudsOut, OneByThree, Location{0, 0}),
+				blue,
+			},
+			want: []string{"wrong color at {0 3}"},
+		},
+	} {
+		var got []string
+		for _, v := range ValidatePlan(brickPlan{uniformIdeal{StudsOut, 1, 4, BrightRed}, test.bricks}) {
+			got = append(got, v.String())
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("for %q wanted %v got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestCreateGridMosaicReportsSolverErrors(t *testing.T) {
+	failing := func(g *Grid, pieces []MosaicPiece) (Solution, error) {
+		return Solution{g.Clone(), map[Location]MosaicPiece{}}, errors.New("gave up")
+	}
+	plan, err := CreateGridMosaic(uniformIdeal{StudsOut, 1, 2, BrightRed}, failing, MosaicOptions{})
+	errs, ok := err.(SolveErrors)
+	if !ok || len(errs) != 1 || errs[0].Color != BrightRed {
+		t.Fatalf("wanted an error for BrightRed got %v", err)
+	}
+	if got := len(ValidatePlan(plan)); got != 2 {
+		t.Errorf("wanted 2 uncovered locations got %d", got)
+	}
+}